@@ -0,0 +1,141 @@
+package keystore
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// EntryOption configures RenameAlias, CopyEntry and MoveEntry.
+type EntryOption func(*entryOptions)
+
+type entryOptions struct {
+	overwrite bool
+}
+
+// WithOverwrite allows to replace an existing entry by the destination alias.
+func WithOverwrite() EntryOption {
+	return func(o *entryOptions) { o.overwrite = true }
+}
+
+// RenameAlias changes alias of the entry. Private keys are moved encrypted, so no password is needed.
+// ErrAliasExists is returned if the keystore has an entry by newAlias unless WithOverwrite is used.
+func (ks KeyStore) RenameAlias(oldAlias, newAlias string, opts ...EntryOption) error {
+	return ks.MoveEntry(ks, oldAlias, newAlias, opts...)
+}
+
+// CopyEntry copies the entry by srcAlias into dst by dstAlias. Private keys are copied encrypted,
+// so no password is needed. Aliases are converted by settings of the corresponding keystore.
+// ErrAliasExists is returned if dst has an entry by dstAlias unless WithOverwrite is used.
+// ErrPasswordEncodingMismatch is returned for PrivateKeyEntry if only one of the keystores is created using
+// WithLegacyPasswordEncoding option, because keys with non-ASCII passwords could not be decrypted by dst.
+func (ks KeyStore) CopyEntry(dst KeyStore, srcAlias, dstAlias string, opts ...EntryOption) error {
+	_, err := ks.copyEntry(dst, srcAlias, dstAlias, opts)
+
+	return err
+}
+
+// MoveEntry copies the entry the same way CopyEntry does and deletes it from the keystore.
+func (ks KeyStore) MoveEntry(dst KeyStore, srcAlias, dstAlias string, opts ...EntryOption) error {
+	same, err := ks.copyEntry(dst, srcAlias, dstAlias, opts)
+	if err != nil {
+		return err
+	}
+
+	if !same {
+		ks.DeleteEntry(srcAlias)
+	}
+
+	return nil
+}
+
+// copyEntry returns true if the source and the destination are the same entry.
+func (ks KeyStore) copyEntry(dst KeyStore, srcAlias, dstAlias string, opts []EntryOption) (bool, error) {
+	var o entryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	src := ks.convertAlias(srcAlias)
+
+	e, ok := ks.m[src]
+	if !ok {
+		return false, ErrEntryNotFound
+	}
+
+	target := dst.convertAlias(dstAlias)
+
+	if sameMap(ks.m, dst.m) && src == target {
+		return true, nil
+	}
+
+	if _, ok := dst.m[target]; ok && !o.overwrite {
+		return false, fmt.Errorf("alias %q: %w", dstAlias, ErrAliasExists)
+	}
+
+	if _, ok := e.(PrivateKeyEntry); ok && ks.legacyPasswordEncoding != dst.legacyPasswordEncoding {
+		return false, fmt.Errorf("alias %q: %w", srcAlias, ErrPasswordEncodingMismatch)
+	}
+
+	dst.m[target] = cloneEntry(e)
+
+	return false, nil
+}
+
+func sameMap(a, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// cloneEntry returns a deep copy of the entry, so zeroing one copy does not affect another.
+func cloneEntry(e interface{}) interface{} {
+	switch typedEntry := e.(type) {
+	case PrivateKeyEntry:
+		typedEntry.PrivateKey = cloneBytes(typedEntry.PrivateKey)
+		typedEntry.CertificateChain = cloneCertificates(typedEntry.CertificateChain)
+		typedEntry.Attributes = cloneAttributes(typedEntry.Attributes)
+
+		return typedEntry
+	case TrustedCertificateEntry:
+		typedEntry.Certificate.Content = cloneBytes(typedEntry.Certificate.Content)
+		typedEntry.Attributes = cloneAttributes(typedEntry.Attributes)
+
+		return typedEntry
+	case SecretKeyEntry:
+		typedEntry.Key = cloneBytes(typedEntry.Key)
+		typedEntry.CertificateChain = cloneCertificates(typedEntry.CertificateChain)
+		typedEntry.Attributes = cloneAttributes(typedEntry.Attributes)
+
+		return typedEntry
+	case SealedEntry:
+		typedEntry.Content = cloneBytes(typedEntry.Content)
+		typedEntry.CertificateChain = cloneCertificates(typedEntry.CertificateChain)
+
+		return typedEntry
+	default:
+		return e
+	}
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
+
+func cloneCertificates(certs []Certificate) []Certificate {
+	if certs == nil {
+		return nil
+	}
+
+	cloned := make([]Certificate, len(certs))
+	for i, c := range certs {
+		cloned[i] = Certificate{Type: c.Type, Content: cloneBytes(c.Content)}
+	}
+
+	return cloned
+}
+
+func cloneAttributes(m map[string]string) map[string]string {
+	return mergeAttributes(m)
+}
@@ -0,0 +1,109 @@
+package keystore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameAlias(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"key": "password"}, "cert")
+
+	require.NoError(t, ks.RenameAlias("key", "renamed"))
+	assert.Equal(t, []string{"cert", "renamed"}, ks.Aliases())
+
+	pke, err := ks.GetPrivateKeyEntry("renamed", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+
+	err = ks.RenameAlias("renamed", "CERT")
+	require.ErrorIs(t, err, ErrAliasExists)
+
+	require.NoError(t, ks.RenameAlias("renamed", "CERT", WithOverwrite()))
+	assert.Equal(t, []string{"cert"}, ks.Aliases())
+	assert.True(t, ks.IsPrivateKeyEntry("cert"))
+
+	require.NoError(t, ks.RenameAlias("Cert", "cert"))
+	assert.Equal(t, []string{"cert"}, ks.Aliases())
+
+	require.ErrorIs(t, ks.RenameAlias("missing", "other"), ErrEntryNotFound)
+}
+
+func TestRenameAliasCaseExact(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"key": "password"}, "cert", WithCaseExactAliases())
+
+	require.NoError(t, ks.RenameAlias("key", "Key"))
+	assert.Equal(t, []string{"Key", "cert"}, ks.Aliases())
+}
+
+func TestCopyEntry(t *testing.T) {
+	src := newTestKeyStore(t, map[string]string{"key": "password"}, "cert")
+	dst := New(WithOrderedAliases(), WithCaseExactAliases())
+
+	require.NoError(t, src.CopyEntry(dst, "key", "Copied"))
+	assert.Equal(t, []string{"cert", "key"}, src.Aliases())
+	assert.Equal(t, []string{"Copied"}, dst.Aliases())
+
+	pke, err := dst.GetPrivateKeyEntry("Copied", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+
+	zeroing(dst.m["Copied"].(PrivateKeyEntry).PrivateKey)
+
+	_, err = src.GetPrivateKeyEntry("key", []byte("password"))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, src.CopyEntry(dst, "cert", "Copied"), ErrAliasExists)
+	require.NoError(t, src.CopyEntry(dst, "cert", "Copied", WithOverwrite()))
+	assert.True(t, dst.IsTrustedCertificateEntry("Copied"))
+
+	require.NoError(t, src.CopyEntry(src, "cert", "cert-copy"))
+	assert.Equal(t, []string{"cert", "cert-copy", "key"}, src.Aliases())
+}
+
+func TestMoveEntry(t *testing.T) {
+	src := newTestKeyStore(t, map[string]string{"key": "password"}, "cert")
+	dst := New(WithOrderedAliases())
+
+	require.NoError(t, src.MoveEntry(dst, "cert", "cert"))
+	assert.Equal(t, []string{"key"}, src.Aliases())
+	assert.Equal(t, []string{"cert"}, dst.Aliases())
+
+	require.NoError(t, dst.MoveEntry(src, "cert", "key", WithOverwrite()))
+	assert.Equal(t, []string{"key"}, src.Aliases())
+	assert.Empty(t, dst.Aliases())
+	assert.True(t, src.IsTrustedCertificateEntry("key"))
+}
+
+func TestCopyEntryPasswordEncoding(t *testing.T) {
+	src := New(WithLegacyPasswordEncoding())
+	password := []byte("pässwörd")
+
+	require.NoError(t, src.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+	}, password))
+
+	require.NoError(t, src.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: X509CertificateType, Content: readCertificate(t)},
+	}))
+
+	dst := New()
+
+	require.ErrorIs(t, src.CopyEntry(dst, "key", "key"), ErrPasswordEncodingMismatch)
+	require.ErrorIs(t, src.MoveEntry(dst, "key", "key"), ErrPasswordEncodingMismatch)
+	assert.True(t, src.IsPrivateKeyEntry("key"))
+	assert.Empty(t, dst.Aliases())
+
+	require.NoError(t, src.CopyEntry(dst, "cert", "cert"))
+
+	legacy := New(WithLegacyPasswordEncoding())
+	require.NoError(t, src.MoveEntry(legacy, "key", "key"))
+
+	pke, err := legacy.GetPrivateKeyEntry("key", password)
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+}
@@ -0,0 +1,181 @@
+package keystore
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Well-known attribute names. Other attributes of PKCS#12 bags are named by dotted OID.
+// Attributes are entry metadata by name, formats which can not hold them drop them on store.
+const (
+	// AttributeFriendlyName is PKCS#9 friendlyName, the alias of PKCS#12 entry.
+	AttributeFriendlyName = "friendlyName"
+	// AttributeLocalKeyID is PKCS#9 localKeyId in hex, it links PKCS#12 key with its certificate.
+	AttributeLocalKeyID = "localKeyId"
+	// AttributeTrustedKeyUsage is comma separated dotted OIDs of extended key usages Java trusts the certificate for.
+	AttributeTrustedKeyUsage = "trustedKeyUsage"
+)
+
+// hexAttributePrefix marks attribute value which is hex of DER encoded values.
+const hexAttributePrefix = "#"
+
+// decodePKCS12Attributes converts bag attributes to entry attributes. Values of attributes other than
+// well-known are kept as text if the attribute has single UTF8String value or as hex of DER prefixed by #.
+func decodePKCS12Attributes(attributes []pkcs12Attribute) (map[string]string, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]string, len(attributes))
+
+	for _, attribute := range attributes {
+		switch {
+		case attribute.ID.Equal(oidFriendlyName):
+			var value asn1.RawValue
+			if _, err := asn1.Unmarshal(attribute.Value.Bytes, &value); err != nil {
+				return nil, fmt.Errorf("unmarshal friendly name: %w", err)
+			}
+
+			name, err := decodeBMPString(value.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("decode friendly name: %w", err)
+			}
+
+			m[AttributeFriendlyName] = name
+		case attribute.ID.Equal(oidLocalKeyID):
+			var id []byte
+			if _, err := asn1.Unmarshal(attribute.Value.Bytes, &id); err != nil {
+				return nil, fmt.Errorf("unmarshal local key id: %w", err)
+			}
+
+			m[AttributeLocalKeyID] = hex.EncodeToString(id)
+		case attribute.ID.Equal(oidTrustedKeyUsage):
+			var usages []string
+
+			for rest := attribute.Value.Bytes; len(rest) > 0; {
+				var (
+					usage asn1.ObjectIdentifier
+					err   error
+				)
+
+				if rest, err = asn1.Unmarshal(rest, &usage); err != nil {
+					return nil, fmt.Errorf("unmarshal trusted key usage: %w", err)
+				}
+
+				usages = append(usages, usage.String())
+			}
+
+			m[AttributeTrustedKeyUsage] = strings.Join(usages, ",")
+		default:
+			m[attribute.ID.String()] = decodeAttributeValue(attribute.Value.Bytes)
+		}
+	}
+
+	return m, nil
+}
+
+func decodeAttributeValue(der []byte) string {
+	var value asn1.RawValue
+
+	rest, err := asn1.Unmarshal(der, &value)
+	if err == nil && len(rest) == 0 && value.Class == asn1.ClassUniversal && value.Tag == asn1.TagUTF8String &&
+		!strings.HasPrefix(string(value.Bytes), hexAttributePrefix) {
+		return string(value.Bytes)
+	}
+
+	return hexAttributePrefix + hex.EncodeToString(der)
+}
+
+// encodePKCS12Attributes converts entry attributes to bag attributes sorted by name.
+// Attributes which are neither well-known nor named by dotted OID are library tags and are skipped.
+func encodePKCS12Attributes(m map[string]string) ([]pkcs12Attribute, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var attributes []pkcs12Attribute
+
+	for _, name := range names {
+		value := m[name]
+
+		switch name {
+		case AttributeFriendlyName:
+			attributes = append(attributes, newFriendlyNameAttribute(value))
+		case AttributeLocalKeyID:
+			id, err := hex.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("decode local key id: %w", err)
+			}
+
+			attributes = append(attributes, newLocalKeyIDAttribute(id))
+		case AttributeTrustedKeyUsage:
+			attribute, err := newTrustedKeyUsageAttribute(value)
+			if err != nil {
+				return nil, err
+			}
+
+			attributes = append(attributes, attribute)
+		default:
+			oid, err := parseOID(name)
+			if err != nil {
+				continue
+			}
+
+			der, err := encodeAttributeValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("encode attribute %s: %w", name, err)
+			}
+
+			attributes = append(attributes, pkcs12Attribute{ID: oid, Value: setValue(der)})
+		}
+	}
+
+	return attributes, nil
+}
+
+func encodeAttributeValue(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, hexAttributePrefix) {
+		return asn1.MarshalWithParams(value, "utf8")
+	}
+
+	der, err := hex.DecodeString(strings.TrimPrefix(value, hexAttributePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("decode hex value: %w", err)
+	}
+
+	for rest := der; len(rest) > 0; {
+		if rest, err = asn1.Unmarshal(rest, &asn1.RawValue{}); err != nil {
+			return nil, fmt.Errorf("got invalid der value: %w", err)
+		}
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("got empty value")
+	}
+
+	return der, nil
+}
+
+// mergeAttributes returns attributes of all maps, later maps take precedence.
+func mergeAttributes(ms ...map[string]string) map[string]string {
+	var merged map[string]string
+
+	for _, m := range ms {
+		for name, value := range m {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+
+			merged[name] = value
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,111 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPKCS12Attributes(t *testing.T) {
+	f, err := os.Open("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	ks := New()
+	require.NoError(t, ks.LoadPKCS12(f, []byte("password"), nil))
+
+	pke, err := ks.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, "server", pke.Attributes[AttributeFriendlyName])
+	assert.Len(t, pke.Attributes[AttributeLocalKeyID], 40)
+}
+
+func TestPKCS12AttributesRoundTrip(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"server": "password"}, "ca")
+	setTestAttributes(ks)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ks.StorePKCS12(&buf, []byte("password"), UnlockOptions{}))
+
+	loaded := New()
+	require.NoError(t, loaded.LoadPKCS12(&buf, []byte("password"), nil))
+
+	pke, err := loaded.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		AttributeFriendlyName: "server",
+		AttributeLocalKeyID:   "0102",
+		"1.2.3.4":             "text",
+		"1.2.3.5":             "#020105",
+	}, pke.Attributes)
+
+	tce, err := loaded.GetTrustedCertificateEntry("ca")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		AttributeFriendlyName:    "ca",
+		AttributeTrustedKeyUsage: "1.3.6.1.5.5.7.3.1,1.3.6.1.5.5.7.3.2",
+	}, tce.Attributes)
+}
+
+func TestBCFKSAttributesRoundTrip(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"server": "password"}, "ca")
+	setTestAttributes(ks)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ks.StoreBCFKS(&buf, []byte("password"), UnlockOptions{}))
+
+	loaded := New()
+	require.NoError(t, loaded.LoadBCFKS(&buf, []byte("password"), nil))
+
+	expected, err := ks.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+
+	actual, err := loaded.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, expected.Attributes, actual.Attributes)
+
+	tce, err := loaded.GetTrustedCertificateEntry("ca")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.5.5.7.3.1,1.3.6.1.5.5.7.3.2", tce.Attributes[AttributeTrustedKeyUsage])
+
+	assert.Equal(t, map[string]string{"comment": "free text"}, decodeBCFKSComment("free text"))
+}
+
+func TestEncodePKCS12AttributesErrors(t *testing.T) {
+	for _, m := range []map[string]string{
+		{AttributeLocalKeyID: "not hex"},
+		{AttributeTrustedKeyUsage: "any"},
+		{"1.2.3.4": "#zz"},
+		{"1.2.3.4": "#02"},
+	} {
+		_, err := encodePKCS12Attributes(m)
+		require.Error(t, err, m)
+	}
+
+	attributes, err := encodePKCS12Attributes(map[string]string{"owner": "team-a"})
+	require.NoError(t, err)
+	assert.Empty(t, attributes)
+}
+
+// setTestAttributes sets attributes of "server" and "ca" entries made by newTestKeyStore.
+func setTestAttributes(ks KeyStore) {
+	pke := ks.m["server"].(PrivateKeyEntry)
+	pke.Attributes = map[string]string{
+		"owner":             "team-a",
+		"rotation":          "90d",
+		AttributeLocalKeyID: "0102",
+		"1.2.3.4":           "text",
+		"1.2.3.5":           "#020105",
+	}
+	ks.m["server"] = pke
+
+	tce := ks.m["ca"].(TrustedCertificateEntry)
+	tce.Attributes = map[string]string{AttributeTrustedKeyUsage: "1.3.6.1.5.5.7.3.1,1.3.6.1.5.5.7.3.2"}
+	ks.m["ca"] = tce
+}
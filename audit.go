@@ -0,0 +1,209 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// AuditOptions configures AuditPasswords.
+type AuditOptions struct {
+	// Workers is the number of goroutines trying the words, runtime.NumCPU by default.
+	Workers int
+}
+
+// AuditReport reports words of the wordlist AuditPasswords found by their index in the wordlist.
+type AuditReport struct {
+	// StorePassword is the index of the store password or -1 if the wordlist does not contain it.
+	StorePassword int
+	// KeyPasswords are indexes of key passwords by alias of private key entries.
+	// Entries which key password is not in the wordlist are absent.
+	KeyPasswords map[string]int
+}
+
+// WeakAliases returns aliases of private key entries which key password is in the wordlist sorted alphabetically.
+func (r AuditReport) WeakAliases() []string {
+	aliases := make([]string, 0, len(r.KeyPasswords))
+	for alias := range r.KeyPasswords {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// AuditPasswords reads JKS keystore representation from r and tries every word of the wordlist as the store
+// password against the keystore digest and as the key password of every private key entry. The words are
+// tried in parallel, the first matching word of the wordlist is reported. The keystore is not changed,
+// only its options are used, e.g. WithLegacyPasswordEncoding.
+func (ks KeyStore) AuditPasswords(r io.Reader, wordlist [][]byte, opts AuditOptions) (AuditReport, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return AuditReport{}, fmt.Errorf("read keystore: %w", err)
+	}
+
+	entries := ks
+	entries.m = make(map[string]interface{})
+
+	br := bytes.NewReader(data)
+	if err := entries.readEntries(decoder{r: br, h: sha1.New()}); err != nil {
+		return AuditReport{}, err
+	}
+
+	if br.Len() < sha1.Size {
+		return AuditReport{}, fmt.Errorf("read digest: %w", io.ErrUnexpectedEOF)
+	}
+
+	bodyLen := len(data) - br.Len()
+
+	a := &auditor{
+		ks:      entries,
+		body:    data[:bodyLen],
+		digest:  data[bodyLen : bodyLen+sha1.Size],
+		aliases: []string{},
+		found:   []int{-1},
+	}
+
+	for _, alias := range entries.Aliases() {
+		if entries.IsPrivateKeyEntry(alias) {
+			a.aliases = append(a.aliases, alias)
+			a.found = append(a.found, -1)
+		}
+	}
+
+	a.run(wordlist, opts.Workers)
+
+	report := AuditReport{StorePassword: a.found[0], KeyPasswords: make(map[string]int)}
+
+	for i, alias := range a.aliases {
+		if a.found[i+1] >= 0 {
+			report.KeyPasswords[alias] = a.found[i+1]
+		}
+	}
+
+	return report, nil
+}
+
+// auditor tries words against targets: the store digest first and then the private key entries by aliases.
+type auditor struct {
+	ks      KeyStore
+	body    []byte
+	digest  []byte
+	aliases []string
+
+	mu        sync.Mutex
+	found     []int
+	remaining int
+}
+
+func (a *auditor) run(wordlist [][]byte, workers int) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	a.remaining = len(a.found)
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				a.try(i, wordlist[i])
+			}
+		}()
+	}
+
+	for i := range wordlist {
+		if a.done() {
+			break
+		}
+
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+}
+
+func (a *auditor) try(index int, word []byte) {
+	for target := range a.found {
+		if !a.needs(target, index) {
+			continue
+		}
+
+		var matched bool
+
+		if target == 0 {
+			matched = a.matchStorePassword(word)
+		} else {
+			matched = a.matchKeyPassword(a.aliases[target-1], word)
+		}
+
+		if matched {
+			a.record(target, index)
+		}
+	}
+}
+
+func (a *auditor) matchStorePassword(word []byte) bool {
+	passwordBytes := passwordBytes(word, a.ks.legacyPasswordEncoding)
+	defer zeroing(passwordBytes)
+
+	h := sha1.New()
+	h.Write(passwordBytes)
+	h.Write(whitenerMessage)
+	h.Write(a.body)
+
+	return bytes.Equal(h.Sum(nil), a.digest)
+}
+
+func (a *auditor) matchKeyPassword(alias string, word []byte) bool {
+	pke, _ := a.ks.m[alias].(PrivateKeyEntry)
+
+	plainKey, err := a.ks.keyProtector().decrypt(pke.PrivateKey, word)
+	if err != nil {
+		return false
+	}
+
+	destroySecret(plainKey)
+
+	return true
+}
+
+// needs reports whether the word by index may be the first matching one for the target.
+func (a *auditor) needs(target, index int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.found[target] < 0 || index < a.found[target]
+}
+
+func (a *auditor) record(target, index int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.found[target] < 0 {
+		a.remaining--
+		a.found[target] = index
+	} else if index < a.found[target] {
+		a.found[target] = index
+	}
+}
+
+func (a *auditor) done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.remaining == 0
+}
@@ -0,0 +1,44 @@
+package keystore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditPasswords(t *testing.T) {
+	ks := New()
+	pke := PrivateKeyEntry{CreationTime: time.Now(), PrivateKey: readPrivateKey(t)}
+
+	require.NoError(t, ks.SetPrivateKeyEntry("a", pke, []byte("password")))
+	require.NoError(t, ks.SetPrivateKeyEntry("b", pke, []byte("Strong-Unique-Password")))
+	require.NoError(t, ks.SetPrivateKeyEntry("c", pke, []byte("changeit")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("changeit")))
+
+	wordlist := [][]byte{[]byte("123456"), []byte("password"), []byte("changeit"), []byte("password")}
+
+	for _, workers := range []int{0, 1, 3} {
+		report, err := New().AuditPasswords(bytes.NewReader(buf.Bytes()), wordlist, AuditOptions{Workers: workers})
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.StorePassword)
+		assert.Equal(t, map[string]int{"a": 1, "c": 2}, report.KeyPasswords)
+		assert.Equal(t, []string{"a", "c"}, report.WeakAliases())
+	}
+
+	report, err := New().AuditPasswords(bytes.NewReader(buf.Bytes()), wordlist[:2], AuditOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, -1, report.StorePassword)
+	assert.Equal(t, []string{"a"}, report.WeakAliases())
+
+	_, err = New().AuditPasswords(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), wordlist, AuditOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,851 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// BouncyCastle FIPS keystore constants, see org.bouncycastle.jcajce.provider.keystore.bcfks.BcFKSKeyStoreSpi.
+const (
+	bcfksVersion = 1
+
+	bcfksCertificate         = 0
+	bcfksPrivateKey          = 1
+	bcfksSecretKey           = 2
+	bcfksProtectedPrivateKey = 3
+	bcfksProtectedSecretKey  = 4
+
+	bcfksIterations = 16384
+	bcfksSaltLen    = 64
+	bcfksMACKeyLen  = 64
+	bcfksNonceLen   = 12
+	bcfksTagLen     = 16
+
+	// bcfksMaxKeyLen, bcfksMaxScryptCost and bcfksMaxScryptWork limit key derivation parameters read from files.
+	bcfksMaxKeyLen     = 64
+	bcfksMaxScryptCost = 1 << 20
+	bcfksMaxScryptWork = 1 << 24
+
+	bcfksStoreEncryption      = "STORE_ENCRYPTION"
+	bcfksPrivateKeyEncryption = "PRIVATE_KEY_ENCRYPTION"
+	bcfksSecretKeyEncryption  = "SECRET_KEY_ENCRYPTION"
+	bcfksIntegrityCheck       = "INTEGRITY_CHECK"
+
+	rawKeyFormat = "RAW"
+
+	bcfksCommentAttribute = "comment"
+)
+
+var (
+	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	oidAES256GCM     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+	oidAES256CCM     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 47}
+	oidAES256WrapPad = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 48}
+)
+
+var secretKeyAlgorithms = []struct {
+	name string
+	oid  asn1.ObjectIdentifier
+}{
+	{"AES", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1}},
+	{"DESede", asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}},
+	{"HmacSHA1", oidHMACWithSHA1},
+	{"HmacSHA256", oidHMACWithSHA256},
+	{"HmacSHA384", oidHMACWithSHA384},
+	{"HmacSHA512", oidHMACWithSHA512},
+}
+
+type bcfksObjectStore struct {
+	StoreData      asn1.RawValue
+	IntegrityCheck asn1.RawValue
+}
+
+type bcfksEncryptedObjectStoreData struct {
+	EncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent    []byte
+}
+
+type bcfksObjectStoreData struct {
+	Version            int
+	IntegrityAlgorithm pkix.AlgorithmIdentifier
+	CreationDate       time.Time `asn1:"generalized"`
+	LastModifiedDate   time.Time `asn1:"generalized"`
+	ObjectDataSequence []bcfksObjectData
+	Comment            string `asn1:"utf8,optional"`
+}
+
+type bcfksObjectData struct {
+	Type             int
+	Identifier       string    `asn1:"utf8"`
+	CreationDate     time.Time `asn1:"generalized"`
+	LastModifiedDate time.Time `asn1:"generalized"`
+	Data             []byte
+	Comment          string `asn1:"utf8,optional"`
+}
+
+type bcfksPbkdMacIntegrityCheck struct {
+	MacAlgorithm  pkix.AlgorithmIdentifier
+	PbkdAlgorithm pkix.AlgorithmIdentifier
+	Mac           []byte
+}
+
+type bcfksEncryptedPrivateKeyData struct {
+	EncryptedPrivateKeyInfo encryptedPrivateKeyInfo
+	Certificates            []asn1.RawValue
+}
+
+type bcfksEncryptedSecretKeyData struct {
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKeyData       []byte
+}
+
+type bcfksSecretKeyData struct {
+	KeyAlgorithm asn1.ObjectIdentifier
+	KeyBytes     []byte
+}
+
+// aeadParams are CCMParameters and GCMParameters of RFC 5084.
+type aeadParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+func (p scryptParams) check() error {
+	if p.CostParameter > bcfksMaxScryptCost || p.BlockSize <= 0 || p.ParallelizationParameter <= 0 ||
+		int64(p.CostParameter)*int64(p.BlockSize)*int64(p.ParallelizationParameter) > bcfksMaxScryptWork {
+		return fmt.Errorf("got invalid scrypt parameters N=%d, r=%d, p=%d",
+			p.CostParameter, p.BlockSize, p.ParallelizationParameter)
+	}
+
+	return nil
+}
+
+// StoreBCFKS writes the keystore as BouncyCastle FIPS keystore protected with password.
+// Private keys are decrypted trying passwords from opts and then the store password,
+// ErrIncorrectPassword is returned if any of them can not be decrypted. Every private key is encrypted
+// with the password which decrypted it, secret keys are encrypted with the store password.
+// The store and the keys are encrypted with AES-256-CCM using key derived by PBKDF2 with HMAC-SHA512,
+// the integrity is protected with HMAC-SHA512.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) StoreBCFKS(w io.Writer, password []byte, opts UnlockOptions) error {
+	if len(password) < ks.minPasswordLen {
+		return fmt.Errorf("password must be at least %d characters: %w", ks.minPasswordLen, ErrShortPassword)
+	}
+
+	opts.Candidates = append(append([][]byte{}, opts.Candidates...), password)
+
+	unlocked := ks.UnlockPrivateKeyEntries(opts)
+	defer unlocked.Zeroing()
+
+	if len(unlocked.Locked) > 0 {
+		return fmt.Errorf("decrypt private key %q: %w", unlocked.Locked[0].Alias, unlocked.Locked[0].Err)
+	}
+
+	aliases := ks.Aliases()
+	objects := make([]bcfksObjectData, 0, len(aliases))
+
+	for _, alias := range aliases {
+		od, err := ks.bcfksObject(alias, unlocked, password)
+		if err != nil {
+			return fmt.Errorf("encode entry %q: %w", alias, err)
+		}
+
+		objects = append(objects, od)
+	}
+
+	macAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA512, Parameters: asn1.NullRawValue}
+	now := time.Now().UTC()
+
+	storeData, err := asn1.Marshal(bcfksObjectStoreData{
+		Version:            bcfksVersion,
+		IntegrityAlgorithm: macAlgorithm,
+		CreationDate:       now,
+		LastModifiedDate:   now,
+		ObjectDataSequence: objects,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal object store data: %w", err)
+	}
+
+	defer zeroing(storeData)
+
+	algorithm, encrypted, err := ks.bcfksEncrypt(storeData, password, bcfksStoreEncryption)
+	if err != nil {
+		return fmt.Errorf("encrypt object store data: %w", err)
+	}
+
+	encryptedStoreData, err := asn1.Marshal(bcfksEncryptedObjectStoreData{
+		EncryptionAlgorithm: algorithm,
+		EncryptedContent:    encrypted,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal encrypted object store data: %w", err)
+	}
+
+	kdf, err := ks.newBCFKSKDF(bcfksMACKeyLen)
+	if err != nil {
+		return err
+	}
+
+	key, err := bcfksDeriveKey(kdf, password, bcfksIntegrityCheck, bcfksMACKeyLen)
+	if err != nil {
+		return fmt.Errorf("derive mac key: %w", err)
+	}
+
+	defer zeroing(key)
+
+	mac := hmac.New(sha512.New, key)
+	mac.Write(encryptedStoreData)
+
+	integrityCheck, err := asn1.Marshal(bcfksPbkdMacIntegrityCheck{
+		MacAlgorithm:  macAlgorithm,
+		PbkdAlgorithm: kdf,
+		Mac:           mac.Sum(nil),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal integrity check: %w", err)
+	}
+
+	der, err := asn1.Marshal(bcfksObjectStore{
+		StoreData:      asn1.RawValue{FullBytes: encryptedStoreData},
+		IntegrityCheck: asn1.RawValue{FullBytes: integrityCheck},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal object store: %w", err)
+	}
+
+	if _, err := w.Write(der); err != nil {
+		return fmt.Errorf("write object store: %w", err)
+	}
+
+	return nil
+}
+
+func (ks KeyStore) bcfksObject(alias string, unlocked UnlockResult, password []byte) (bcfksObjectData, error) {
+	var (
+		od = bcfksObjectData{Identifier: alias}
+
+		creationTime time.Time
+		attributes   map[string]string
+		err          error
+	)
+
+	switch typedEntry := ks.m[alias].(type) {
+	case TrustedCertificateEntry:
+		if !isX509CertificateType(typedEntry.Certificate.Type) {
+			return od, fmt.Errorf("got unsupported certificate type %q", typedEntry.Certificate.Type)
+		}
+
+		creationTime, attributes = typedEntry.CreationTime, typedEntry.Attributes
+		od.Type = bcfksCertificate
+		od.Data = typedEntry.Certificate.Content
+	case PrivateKeyEntry:
+		creationTime, attributes = typedEntry.CreationTime, typedEntry.Attributes
+		od.Type = bcfksPrivateKey
+		od.Data, err = ks.marshalBCFKSPrivateKey(unlocked.Entries[alias], unlocked.Passwords[alias])
+	case SecretKeyEntry:
+		creationTime, attributes = typedEntry.CreationTime, typedEntry.Attributes
+		od.Type = bcfksSecretKey
+		od.Data, err = ks.marshalBCFKSSecretKey(typedEntry, password)
+	default:
+		return od, errors.New("got invalid entry")
+	}
+
+	if err != nil {
+		return od, err
+	}
+
+	od.CreationDate = creationTime.UTC()
+	od.LastModifiedDate = od.CreationDate
+
+	od.Comment, err = encodeBCFKSComment(attributes)
+
+	return od, err
+}
+
+// encodeBCFKSComment keeps attributes in the comment of the object as JSON, BouncyCastle ignores the comment.
+func encodeBCFKSComment(attributes map[string]string) (string, error) {
+	if len(attributes) == 0 {
+		return "", nil
+	}
+
+	comment, err := json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("marshal attributes: %w", err)
+	}
+
+	return string(comment), nil
+}
+
+// decodeBCFKSComment returns attributes from the comment of the object.
+// The comment which is not JSON object is returned as the comment attribute.
+func decodeBCFKSComment(comment string) map[string]string {
+	if comment == "" {
+		return nil
+	}
+
+	var attributes map[string]string
+	if err := json.Unmarshal([]byte(comment), &attributes); err != nil {
+		return map[string]string{bcfksCommentAttribute: comment}
+	}
+
+	return attributes
+}
+
+func (ks KeyStore) marshalBCFKSPrivateKey(pke PrivateKeyEntry, password []byte) ([]byte, error) {
+	certificates := make([]asn1.RawValue, 0, len(pke.CertificateChain))
+
+	for i, cert := range pke.CertificateChain {
+		if !isX509CertificateType(cert.Type) {
+			return nil, fmt.Errorf("got unsupported type %q of %d certificate", cert.Type, i)
+		}
+
+		certificates = append(certificates, asn1.RawValue{FullBytes: cert.Content})
+	}
+
+	algorithm, encrypted, err := ks.bcfksEncrypt(pke.PrivateKey, password, bcfksPrivateKeyEncryption)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt private key: %w", err)
+	}
+
+	der, err := asn1.Marshal(bcfksEncryptedPrivateKeyData{
+		EncryptedPrivateKeyInfo: encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: encrypted},
+		Certificates:            certificates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encrypted private key: %w", err)
+	}
+
+	return der, nil
+}
+
+func (ks KeyStore) marshalBCFKSSecretKey(ske SecretKeyEntry, password []byte) ([]byte, error) {
+	oid, err := secretKeyAlgorithmOID(ske.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := asn1.Marshal(bcfksSecretKeyData{KeyAlgorithm: oid, KeyBytes: ske.Key})
+	if err != nil {
+		return nil, fmt.Errorf("marshal secret key: %w", err)
+	}
+
+	defer zeroing(plain)
+
+	algorithm, encrypted, err := ks.bcfksEncrypt(plain, password, bcfksSecretKeyEncryption)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt secret key: %w", err)
+	}
+
+	der, err := asn1.Marshal(bcfksEncryptedSecretKeyData{KeyEncryptionAlgorithm: algorithm, EncryptedKeyData: encrypted})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encrypted secret key: %w", err)
+	}
+
+	return der, nil
+}
+
+// bcfksEncrypt encrypts data with PBES2 using PBKDF2 with HMAC-SHA512 and AES-256-CCM.
+func (ks KeyStore) bcfksEncrypt(data, password []byte, purpose string) (pkix.AlgorithmIdentifier, []byte, error) {
+	kdf, err := ks.newBCFKSKDF(aes256KeyLen)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	nonce := make([]byte, bcfksNonceLen)
+	if _, err := io.ReadFull(ks.r, nonce); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("read nonce: %w", err)
+	}
+
+	ccmParams, err := asn1.Marshal(aeadParams{Nonce: nonce, ICVLen: bcfksTagLen})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("marshal ccm parameters: %w", err)
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: kdf,
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CCM, Parameters: asn1.RawValue{FullBytes: ccmParams}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("marshal pbes2 parameters: %w", err)
+	}
+
+	key, err := bcfksDeriveKey(kdf, password, purpose, aes256KeyLen)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	encrypted, err := ccmSeal(block, nonce, data, nil, bcfksTagLen)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}, encrypted, nil
+}
+
+// newBCFKSKDF returns PBKDF2 with HMAC-SHA512 and random salt.
+func (ks KeyStore) newBCFKSKDF(keyLen int) (pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, bcfksSaltLen)
+	if _, err := io.ReadFull(ks.r, salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, fmt.Errorf("read salt: %w", err)
+	}
+
+	params, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: bcfksIterations,
+		KeyLength:      keyLen,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA512, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, fmt.Errorf("marshal pbkdf2 parameters: %w", err)
+	}
+
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// LoadBCFKS reads BouncyCastle FIPS keystore from r, checks its integrity and adds its entries into the keystore.
+// Private and secret keys are decrypted with passwords from keyPasswords or with the store password
+// if it is nil or returns nil source. Private keys are encrypted with the same password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) LoadBCFKS(r io.Reader, password []byte, keyPasswords KeyPasswordResolver) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read object store: %w", err)
+	}
+
+	var store bcfksObjectStore
+
+	rest, err := asn1.Unmarshal(data, &store)
+	if err != nil {
+		return fmt.Errorf("unmarshal object store: %w", err)
+	}
+
+	if len(rest) > 0 {
+		return errors.New("got trailing data after object store")
+	}
+
+	if err := verifyBCFKSIntegrity(store.IntegrityCheck, store.StoreData.FullBytes, password); err != nil {
+		return err
+	}
+
+	storeData, err := decodeBCFKSStoreData(store.StoreData, password)
+	if err != nil {
+		return err
+	}
+
+	// Entries are added into the keystore only if all of them are decoded.
+	staging := ks
+	staging.m = make(map[string]interface{}, len(storeData.ObjectDataSequence))
+
+	for _, od := range storeData.ObjectDataSequence {
+		alias := ks.convertAlias(od.Identifier)
+		if _, ok := ks.m[alias]; ok {
+			return fmt.Errorf("got duplicate alias %q", od.Identifier)
+		}
+
+		if _, ok := staging.m[alias]; ok {
+			return fmt.Errorf("got duplicate alias %q", od.Identifier)
+		}
+
+		if err := staging.addBCFKSObject(od, password, keyPasswords); err != nil {
+			return fmt.Errorf("decode entry %q: %w", od.Identifier, err)
+		}
+	}
+
+	for alias, entry := range staging.m {
+		ks.m[alias] = entry
+	}
+
+	return nil
+}
+
+func verifyBCFKSIntegrity(check asn1.RawValue, storeData, password []byte) error {
+	if check.Class != asn1.ClassUniversal {
+		return errors.New("got unsupported signature integrity check")
+	}
+
+	var macCheck bcfksPbkdMacIntegrityCheck
+	if _, err := asn1.Unmarshal(check.FullBytes, &macCheck); err != nil {
+		return fmt.Errorf("unmarshal integrity check: %w", err)
+	}
+
+	newHash, err := digestByHMACOID(macCheck.MacAlgorithm.Algorithm)
+	if err != nil {
+		return fmt.Errorf("verify mac: %w", err)
+	}
+
+	key, err := bcfksDeriveKey(macCheck.PbkdAlgorithm, password, bcfksIntegrityCheck, newHash().Size())
+	if err != nil {
+		return fmt.Errorf("derive mac key: %w", err)
+	}
+
+	defer zeroing(key)
+
+	mac := hmac.New(newHash, key)
+	mac.Write(storeData)
+
+	if !hmac.Equal(mac.Sum(nil), macCheck.Mac) {
+		return fmt.Errorf("got invalid mac: %w", ErrIncorrectPassword)
+	}
+
+	return nil
+}
+
+// decodeBCFKSStoreData decrypts object store data if it is encrypted.
+func decodeBCFKSStoreData(raw asn1.RawValue, password []byte) (bcfksObjectStoreData, error) {
+	var (
+		storeData bcfksObjectStoreData
+		first     asn1.RawValue
+	)
+
+	if _, err := asn1.Unmarshal(raw.Bytes, &first); err != nil {
+		return storeData, fmt.Errorf("unmarshal object store data: %w", err)
+	}
+
+	der := raw.FullBytes
+
+	if first.Tag != asn1.TagInteger {
+		var encrypted bcfksEncryptedObjectStoreData
+		if _, err := asn1.Unmarshal(raw.FullBytes, &encrypted); err != nil {
+			return storeData, fmt.Errorf("unmarshal encrypted object store data: %w", err)
+		}
+
+		decrypted, err := bcfksDecrypt(encrypted.EncryptionAlgorithm, encrypted.EncryptedContent,
+			password, bcfksStoreEncryption)
+		if err != nil {
+			return storeData, fmt.Errorf("decrypt object store data: %w", err)
+		}
+
+		defer zeroing(decrypted)
+
+		der = decrypted
+	}
+
+	if _, err := asn1.Unmarshal(der, &storeData); err != nil {
+		return storeData, fmt.Errorf("unmarshal object store data: %w", err)
+	}
+
+	return storeData, nil
+}
+
+func (ks KeyStore) addBCFKSObject(od bcfksObjectData, password []byte, keyPasswords KeyPasswordResolver) error {
+	switch od.Type {
+	case bcfksCertificate:
+		ks.m[ks.convertAlias(od.Identifier)] = TrustedCertificateEntry{
+			CreationTime: od.CreationDate,
+			Certificate:  Certificate{Type: X509CertificateType, Content: od.Data},
+			Attributes:   decodeBCFKSComment(od.Comment),
+		}
+
+		return nil
+	case bcfksPrivateKey, bcfksSecretKey:
+	case bcfksProtectedPrivateKey, bcfksProtectedSecretKey:
+		return errors.New("got unsupported protected key")
+	default:
+		return fmt.Errorf("got unsupported object type %d", od.Type)
+	}
+
+	keyPassword, err := bcfksKeyPassword(od.Identifier, password, keyPasswords)
+	if err != nil {
+		return err
+	}
+
+	defer zeroing(keyPassword)
+
+	if od.Type == bcfksSecretKey {
+		ske, err := decodeBCFKSSecretKey(od, keyPassword)
+		if err != nil {
+			return err
+		}
+
+		ks.m[ks.convertAlias(od.Identifier)] = ske
+
+		return nil
+	}
+
+	var keyData bcfksEncryptedPrivateKeyData
+	if _, err := asn1.Unmarshal(od.Data, &keyData); err != nil {
+		return fmt.Errorf("unmarshal encrypted private key: %w", err)
+	}
+
+	keyInfo := keyData.EncryptedPrivateKeyInfo
+
+	der, err := bcfksDecrypt(keyInfo.Algorithm, keyInfo.EncryptedData, keyPassword, bcfksPrivateKeyEncryption)
+	if err != nil {
+		return fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer zeroing(der)
+
+	pke := PrivateKeyEntry{CreationTime: od.CreationDate, PrivateKey: der, Attributes: decodeBCFKSComment(od.Comment)}
+	for _, cert := range keyData.Certificates {
+		pke.CertificateChain = append(pke.CertificateChain, Certificate{Type: X509CertificateType, Content: cert.FullBytes})
+	}
+
+	if err := ks.SetPrivateKeyEntry(od.Identifier, pke, keyPassword); err != nil {
+		return fmt.Errorf("set private key entry: %w", err)
+	}
+
+	return nil
+}
+
+func decodeBCFKSSecretKey(od bcfksObjectData, password []byte) (SecretKeyEntry, error) {
+	var keyData bcfksEncryptedSecretKeyData
+	if _, err := asn1.Unmarshal(od.Data, &keyData); err != nil {
+		return SecretKeyEntry{}, fmt.Errorf("unmarshal encrypted secret key: %w", err)
+	}
+
+	plain, err := bcfksDecrypt(keyData.KeyEncryptionAlgorithm, keyData.EncryptedKeyData, password, bcfksSecretKeyEncryption)
+	if err != nil {
+		return SecretKeyEntry{}, fmt.Errorf("decrypt secret key: %w", err)
+	}
+
+	defer zeroing(plain)
+
+	var key bcfksSecretKeyData
+	if _, err := asn1.Unmarshal(plain, &key); err != nil {
+		return SecretKeyEntry{}, fmt.Errorf("unmarshal secret key: %w", err)
+	}
+
+	return SecretKeyEntry{
+		CreationTime: od.CreationDate,
+		Algorithm:    secretKeyAlgorithmName(key.KeyAlgorithm),
+		Format:       rawKeyFormat,
+		Key:          key.KeyBytes,
+		Attributes:   decodeBCFKSComment(od.Comment),
+	}, nil
+}
+
+func bcfksKeyPassword(alias string, password []byte, keyPasswords KeyPasswordResolver) ([]byte, error) {
+	if keyPasswords != nil {
+		if source := keyPasswords(alias); source != nil {
+			keyPassword, err := source.Password()
+			if err != nil {
+				return nil, fmt.Errorf("read key password: %w", err)
+			}
+
+			return keyPassword, nil
+		}
+	}
+
+	return append([]byte{}, password...), nil
+}
+
+// bcfksDecrypt decrypts data encrypted with PBES2 using AES-256-CCM, AES-256-GCM or AES-256 key wrap with padding.
+func bcfksDecrypt(algorithm pkix.AlgorithmIdentifier, data, password []byte, purpose string) ([]byte, error) {
+	if !algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("got unsupported encryption algorithm %s", algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("unmarshal pbes2 parameters: %w", err)
+	}
+
+	key, err := bcfksDeriveKey(params.KeyDerivationFunc, password, purpose, aes256KeyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	plain, err := bcfksOpen(block, params.EncryptionScheme, data)
+	if errors.Is(err, errMessageAuthentication) || errors.Is(err, errKeyWrapIntegrity) {
+		return nil, fmt.Errorf("decrypt: %w", ErrIncorrectPassword)
+	}
+
+	return plain, err
+}
+
+func bcfksOpen(block cipher.Block, scheme pkix.AlgorithmIdentifier, data []byte) ([]byte, error) {
+	if scheme.Algorithm.Equal(oidAES256WrapPad) {
+		return kwpUnwrap(block, data)
+	}
+
+	if !scheme.Algorithm.Equal(oidAES256CCM) && !scheme.Algorithm.Equal(oidAES256GCM) {
+		return nil, fmt.Errorf("got unsupported encryption scheme %s", scheme.Algorithm)
+	}
+
+	var params aeadParams
+	if _, err := asn1.Unmarshal(scheme.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("unmarshal aead parameters: %w", err)
+	}
+
+	if scheme.Algorithm.Equal(oidAES256CCM) {
+		return ccmOpen(block, params.Nonce, data, nil, params.ICVLen)
+	}
+
+	var (
+		gcm cipher.AEAD
+		err error
+	)
+
+	switch {
+	case len(params.Nonce) == bcfksNonceLen:
+		gcm, err = cipher.NewGCMWithTagSize(block, params.ICVLen)
+	case params.ICVLen == aes.BlockSize:
+		gcm, err = cipher.NewGCMWithNonceSize(block, len(params.Nonce))
+	default:
+		err = errors.New("got unsupported nonce and tag lengths")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+
+	plain, err := gcm.Open(nil, params.Nonce, data, nil)
+	if err != nil {
+		return nil, errMessageAuthentication
+	}
+
+	return plain, nil
+}
+
+// bcfksDeriveKey derives key with PBKDF2 or scrypt from BMPString password followed by BMPString purpose.
+// keyLen is used if the parameters do not specify key length.
+func bcfksDeriveKey(kdf pkix.AlgorithmIdentifier, password []byte, purpose string, keyLen int) ([]byte, error) {
+	bmp := []byte{}
+	if len(password) > 0 {
+		// BouncyCastle converts an empty password to an empty string without the null terminator.
+		bmp = bmpPassword(password)
+	}
+
+	material := append(bmp, bmpPassword([]byte(purpose))...)
+	defer zeroing(material)
+	defer zeroing(bmp)
+
+	switch {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var params pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("unmarshal pbkdf2 parameters: %w", err)
+		}
+
+		if params.KeyLength > 0 {
+			keyLen = params.KeyLength
+		}
+
+		if keyLen > bcfksMaxKeyLen {
+			return nil, fmt.Errorf("got invalid key length %d", keyLen)
+		}
+
+		if err := checkIterations(params.IterationCount); err != nil {
+			return nil, err
+		}
+
+		prf, err := digestByHMACOID(params.PRF.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+
+		return pbkdf2.Key(material, params.Salt, params.IterationCount, keyLen, prf), nil
+	case kdf.Algorithm.Equal(oidScrypt):
+		var params scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("unmarshal scrypt parameters: %w", err)
+		}
+
+		if params.KeyLength > 0 {
+			keyLen = params.KeyLength
+		}
+
+		if keyLen > bcfksMaxKeyLen {
+			return nil, fmt.Errorf("got invalid key length %d", keyLen)
+		}
+
+		if err := params.check(); err != nil {
+			return nil, err
+		}
+
+		key, err := scrypt.Key(material, params.Salt, params.CostParameter, params.BlockSize,
+			params.ParallelizationParameter, keyLen)
+		if err != nil {
+			return nil, fmt.Errorf("derive scrypt key: %w", err)
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("got unsupported key derivation function %s", kdf.Algorithm)
+	}
+}
+
+func secretKeyAlgorithmName(oid asn1.ObjectIdentifier) string {
+	for _, a := range secretKeyAlgorithms {
+		if a.oid.Equal(oid) {
+			return a.name
+		}
+	}
+
+	return oid.String()
+}
+
+// secretKeyAlgorithmOID returns OID of the algorithm name or parses the name as dotted OID.
+func secretKeyAlgorithmOID(name string) (asn1.ObjectIdentifier, error) {
+	for _, a := range secretKeyAlgorithms {
+		if a.name == name {
+			return a.oid, nil
+		}
+	}
+
+	if oid, err := parseOID(name); err == nil {
+		return oid, nil
+	}
+
+	return nil, fmt.Errorf("got unsupported secret key algorithm %q", name)
+}
+
+// parseOID parses dotted notation of object identifier.
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 { //nolint:gomnd,mnd
+		return nil, fmt.Errorf("got invalid object identifier %q", s)
+	}
+
+	oid := make(asn1.ObjectIdentifier, len(parts))
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("got invalid object identifier %q", s)
+		}
+
+		oid[i] = n
+	}
+
+	return oid, nil
+}
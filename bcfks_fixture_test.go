@@ -0,0 +1,48 @@
+//go:build bouncycastle
+
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadBCFKSFixture loads keystore written by BouncyCastle FIPS from the key and the certificate chain
+// of testdata/keystore.p12:
+//
+//	keytool -importkeystore -srckeystore testdata/keystore.p12 -srcstoretype PKCS12 -srcstorepass password \
+//	  -destkeystore testdata/bc/keystore.bcfks -deststoretype BCFKS -deststorepass password -destkeypass password \
+//	  -providerclass org.bouncycastle.jcajce.provider.BouncyCastleFipsProvider -providerpath bc-fips.jar
+//
+// The test is built with bouncycastle tag only and fails if the fixture is missing.
+func TestLoadBCFKSFixture(t *testing.T) {
+	data, err := os.ReadFile("./testdata/bc/keystore.bcfks")
+	require.NoError(t, err, "fixture must be generated by BouncyCastle FIPS")
+
+	source, err := os.ReadFile("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	expected := New()
+	require.NoError(t, expected.LoadPKCS12(bytes.NewReader(source), []byte("password"), nil))
+
+	ks := New()
+	require.NoError(t, ks.LoadBCFKS(bytes.NewReader(data), []byte("password"), nil))
+	assert.Equal(t, expected.Aliases(), ks.Aliases())
+
+	err = New().LoadBCFKS(bytes.NewReader(data), []byte("wrong"), nil)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	for _, alias := range expected.Aliases() {
+		expectedPKE, err := expected.GetPrivateKeyEntry(alias, []byte("password"))
+		require.NoError(t, err)
+
+		pke, err := ks.GetPrivateKeyEntry(alias, []byte("password"))
+		require.NoError(t, err)
+		assert.Equal(t, expectedPKE.PrivateKey, pke.PrivateKey)
+		assert.Equal(t, expectedPKE.CertificateChain, pke.CertificateChain)
+	}
+}
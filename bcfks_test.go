@@ -0,0 +1,279 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCCM(t *testing.T) {
+	// Test vectors of NIST SP 800-38C appendix C.
+	table := []struct {
+		nonce, additionalData, plain, cipher string
+		tagLen                               int
+	}{
+		{"10111213141516", "0001020304050607", "20212223", "7162015b4dac255d", 4},
+		{
+			"1011121314151617", "000102030405060708090a0b0c0d0e0f",
+			"202122232425262728292a2b2c2d2e2f", "d2a1f0e051ea5f62081a7792073d593d1fc64fbfaccd", 6,
+		},
+		{
+			"101112131415161718191a1b", "000102030405060708090a0b0c0d0e0f10111213",
+			"202122232425262728292a2b2c2d2e2f3031323334353637",
+			"e3b201a9f5b71a7a9b1ceaeccd97e70b6176aad9a4428aa5484392fbc1b09951", 8,
+		},
+	}
+
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f")
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	for _, tt := range table {
+		nonce, _ := hex.DecodeString(tt.nonce)
+		additionalData, _ := hex.DecodeString(tt.additionalData)
+		plain, _ := hex.DecodeString(tt.plain)
+		expected, _ := hex.DecodeString(tt.cipher)
+
+		actual, err := ccmSeal(block, nonce, plain, additionalData, tt.tagLen)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, tt.nonce)
+
+		decrypted, err := ccmOpen(block, nonce, actual, additionalData, tt.tagLen)
+		require.NoError(t, err)
+		assert.Equal(t, plain, decrypted, tt.nonce)
+
+		actual[0] ^= 1
+		_, err = ccmOpen(block, nonce, actual, additionalData, tt.tagLen)
+		require.ErrorIs(t, err, errMessageAuthentication, tt.nonce)
+	}
+}
+
+func TestKWPUnwrap(t *testing.T) {
+	// Test vectors of RFC 5649 section 6.
+	table := []struct {
+		wrapped, key string
+	}{
+		{"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a", "c37b7e6492584340bed12207808941155068f738"},
+		{"afbeb0f07dfbf5419200f2ccb50bb24f", "466f7250617369"},
+	}
+
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	block, err := aes.NewCipher(kek)
+	require.NoError(t, err)
+
+	for _, tt := range table {
+		wrapped, _ := hex.DecodeString(tt.wrapped)
+		expected, _ := hex.DecodeString(tt.key)
+
+		actual, err := kwpUnwrap(block, wrapped)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, tt.key)
+
+		wrapped[len(wrapped)-1] ^= 1
+		_, err = kwpUnwrap(block, wrapped)
+		require.ErrorIs(t, err, errKeyWrapIntegrity, tt.key)
+	}
+}
+
+func TestStoreBCFKSRoundTrip(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"server": "keypassword"}, "ca")
+
+	require.NoError(t, ks.SetSecretKeyEntry("aes", SecretKeyEntry{
+		CreationTime: time.Now(),
+		Algorithm:    "AES",
+		Format:       rawKeyFormat,
+		Key:          bytes.Repeat([]byte{1}, 32),
+	}))
+
+	require.NoError(t, ks.SetSecretKeyEntry("opaque", SecretKeyEntry{
+		CreationTime: time.Now(),
+		Algorithm:    "1.2.3.4",
+		Key:          []byte{1, 2, 3},
+	}))
+
+	var buf bytes.Buffer
+
+	opts := UnlockOptions{Passwords: map[string][]byte{"server": []byte("keypassword")}}
+	require.NoError(t, ks.StoreBCFKS(&buf, []byte("password"), opts))
+
+	keyPasswords := KeyPasswords(map[string]PasswordSource{
+		"server": PasswordFunc(func() ([]byte, error) { return []byte("keypassword"), nil }),
+	}, nil)
+
+	loaded := New(WithOrderedAliases())
+	require.NoError(t, loaded.LoadBCFKS(bytes.NewReader(buf.Bytes()), []byte("password"), keyPasswords))
+	assert.Equal(t, []string{"aes", "ca", "opaque", "server"}, loaded.Aliases())
+
+	expected, err := ks.GetPrivateKeyEntry("server", []byte("keypassword"))
+	require.NoError(t, err)
+
+	actual, err := loaded.GetPrivateKeyEntry("server", []byte("keypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, expected.PrivateKey, actual.PrivateKey)
+	assert.Equal(t, expected.CertificateChain, actual.CertificateChain)
+	assert.True(t, expected.CreationTime.Equal(actual.CreationTime))
+
+	for _, alias := range []string{"aes", "opaque"} {
+		expected, err := ks.GetSecretKeyEntry(alias)
+		require.NoError(t, err)
+
+		actual, err := loaded.GetSecretKeyEntry(alias)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Algorithm, actual.Algorithm)
+		assert.Equal(t, expected.Key, actual.Key)
+		assert.Equal(t, rawKeyFormat, actual.Format)
+	}
+
+	tce, err := loaded.GetTrustedCertificateEntry("ca")
+	require.NoError(t, err)
+	assert.Equal(t, readCertificate(t), tce.Certificate.Content)
+
+	partial := New()
+	err = partial.LoadBCFKS(bytes.NewReader(buf.Bytes()), []byte("password"), nil)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+	assert.Empty(t, partial.Aliases(), "keystore must not be changed on error")
+
+	err = New().LoadBCFKS(bytes.NewReader(buf.Bytes()), []byte("wrong"), keyPasswords)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func TestStoreBCFKSErrors(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"server": "keypassword"}, "ca")
+
+	err := ks.StoreBCFKS(&bytes.Buffer{}, []byte("password"), UnlockOptions{})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	ks.DeleteEntry("server")
+	require.NoError(t, ks.SetSecretKeyEntry("unknown", SecretKeyEntry{Algorithm: "Unknown", Key: []byte{1}}))
+
+	err = ks.StoreBCFKS(&bytes.Buffer{}, []byte("password"), UnlockOptions{})
+	require.ErrorContains(t, err, "unsupported secret key algorithm")
+
+	require.ErrorIs(t, ks.SetSecretKeyEntry("empty", SecretKeyEntry{Algorithm: "AES"}), ErrEmptySecretKey)
+}
+
+func TestLoadBCFKSErrors(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, newTestKeyStore(t, map[string]string{"server": "keypassword"}, "ca").StoreBCFKS(&buf, []byte("password"), UnlockOptions{
+		Candidates: [][]byte{[]byte("keypassword")},
+	}))
+
+	tampered := append([]byte{}, buf.Bytes()...)
+	tampered[len(tampered)/2] ^= 1
+
+	err := New().LoadBCFKS(bytes.NewReader(tampered), []byte("password"), nil)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	err = New().LoadBCFKS(bytes.NewReader(append(buf.Bytes(), 0)), []byte("password"), nil)
+	require.Error(t, err)
+
+	err = New().LoadBCFKS(bytes.NewReader([]byte("garbage")), []byte("password"), nil)
+	require.Error(t, err)
+}
+
+func TestBCFKSDecryptGCM(t *testing.T) {
+	kdf, err := New().newBCFKSKDF(aes256KeyLen)
+	require.NoError(t, err)
+
+	key, err := bcfksDeriveKey(kdf, []byte("password"), bcfksPrivateKeyEncryption, aes256KeyLen)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	gcmParams, err := asn1.Marshal(aeadParams{Nonce: nonce, ICVLen: gcm.Overhead()})
+	require.NoError(t, err)
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: kdf,
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256GCM, Parameters: asn1.RawValue{FullBytes: gcmParams}},
+	})
+	require.NoError(t, err)
+
+	algorithm := pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}
+	encrypted := gcm.Seal(nil, nonce, []byte("private key"), nil)
+
+	decrypted, err := bcfksDecrypt(algorithm, encrypted, []byte("password"), bcfksPrivateKeyEncryption)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("private key"), decrypted)
+
+	_, err = bcfksDecrypt(algorithm, encrypted, []byte("password"), bcfksSecretKeyEncryption)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func TestBCFKSDeriveKey(t *testing.T) {
+	// Expected keys are derived with openssl kdf PBKDF2 from BMPString password followed by BMPString purpose,
+	// the way BouncyCastle concatenates them. Empty password is converted to empty string.
+	table := []struct {
+		password string
+		keyLen   int
+		expected string
+	}{
+		{"password", 64, "20ea1916c5892542e83e76f484c39c02762d9d5842ebad463f5db703ab9de6ff" +
+			"460170f01d086e7b1367b8fd6eee028b47bf8f9a8581d2fe7f394f76d474f947"},
+		{"", 32, "a02e3597b3e4097ea8e5d4145021d680c00393ae44e344a62f915ca3df722cba"},
+	}
+
+	salt := make([]byte, 16)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+
+	params, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: 1024,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA512, Parameters: asn1.NullRawValue},
+	})
+	require.NoError(t, err)
+
+	kdf := pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: params}}
+
+	for _, tt := range table {
+		key, err := bcfksDeriveKey(kdf, []byte(tt.password), bcfksIntegrityCheck, tt.keyLen)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, hex.EncodeToString(key))
+	}
+}
+
+func TestBCFKSDeriveKeyLimits(t *testing.T) {
+	for name, params := range map[string]interface{}{
+		"pbkdf2 iterations": pbkdf2Params{Salt: []byte("salt"), IterationCount: maxPBEIterations + 1},
+		"pbkdf2 key length": pbkdf2Params{Salt: []byte("salt"), IterationCount: 1, KeyLength: 1 << 20},
+		"scrypt cost": scryptParams{
+			Salt: []byte("salt"), CostParameter: 1 << 30, BlockSize: 8, ParallelizationParameter: 1,
+		},
+		"scrypt work": scryptParams{
+			Salt: []byte("salt"), CostParameter: 1 << 20, BlockSize: 8, ParallelizationParameter: 16,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			der, err := asn1.Marshal(params)
+			require.NoError(t, err)
+
+			kdf := pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: der}}
+			if _, ok := params.(scryptParams); ok {
+				kdf.Algorithm = oidScrypt
+			}
+
+			_, err = bcfksDeriveKey(kdf, []byte("password"), bcfksIntegrityCheck, aes256KeyLen)
+			require.ErrorContains(t, err, "got invalid")
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/twofish"
+)
+
+// BouncyCastle BKS and UBER keystore constants, see org.bouncycastle.jcajce.provider.keystore.bc.BcKeyStoreSpi.
+const (
+	bksVersion1 = 1
+	bksVersion2 = 2
+
+	bksEntryNull        = 0
+	bksEntryCertificate = 1
+	bksEntryKey         = 2
+	bksEntrySecret      = 3
+	bksEntrySealed      = 4
+
+	bksKeyPrivate = 0
+	bksKeyPublic  = 1
+	bksKeySecret  = 2
+
+	bksVersion1MACKeyLen = 2
+	uberSaltLen          = 20
+	uberKeyLen           = 32
+	bksMaxIterations     = 1024 << 6
+)
+
+// LoadBKS reads BouncyCastle BKS v1 or v2 keystore from r, checks its integrity and adds its entries
+// into the keystore. Integrity is not checked if password is empty, the same way BouncyCastle does.
+// Not encrypted private keys are encrypted with passwords from keyPasswords or with the store password
+// if it is nil or returns nil source. Encrypted keys become SealedEntry.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) LoadBKS(r io.Reader, password []byte, keyPasswords KeyPasswordResolver) error {
+	d := decoder{r: r, h: sha1.New()}
+
+	version, err := d.readUint32()
+	if err != nil {
+		return fmt.Errorf("read version: %w", err)
+	}
+
+	if version != bksVersion1 && version != bksVersion2 {
+		return fmt.Errorf("got unsupported bks version %d", version)
+	}
+
+	salt, iterations, err := readBKSSaltAndIterations(d)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read entries: %w", err)
+	}
+
+	if len(data) < sha1.Size {
+		return errors.New("got truncated keystore")
+	}
+
+	store, expectedMAC := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+
+	if len(password) > 0 {
+		macKeyLen := sha1.Size
+		if version == bksVersion1 {
+			// BKS v1 derives key of the mac size in bits instead of bytes.
+			macKeyLen = bksVersion1MACKeyLen
+		}
+
+		bmp := bmpPassword(password)
+		key := pkcs12KDF(sha1.New, bmp, salt, iterations, pkcs12MACID, macKeyLen)
+		zeroing(bmp)
+
+		mac := hmac.New(sha1.New, key)
+		mac.Write(store)
+
+		zeroing(key)
+
+		if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+			return fmt.Errorf("got invalid mac: %w", ErrIncorrectPassword)
+		}
+	}
+
+	entries, err := readBKSEntries(store)
+	if err != nil {
+		return err
+	}
+
+	return ks.addBKSEntries(entries, password, keyPasswords)
+}
+
+// LoadUBER reads BouncyCastle UBER keystore from r, decrypts it and checks its integrity and adds its entries
+// into the keystore. Keys are handled the same way LoadBKS does.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) LoadUBER(r io.Reader, password []byte, keyPasswords KeyPasswordResolver) error {
+	d := decoder{r: r, h: sha1.New()}
+
+	version, err := d.readUint32()
+	if err != nil {
+		return fmt.Errorf("read version: %w", err)
+	}
+
+	if version != bksVersion1 && version != bksVersion2 {
+		return fmt.Errorf("got unsupported uber version %d", version)
+	}
+
+	salt, iterations, err := readBKSSaltAndIterations(d)
+	if err != nil {
+		return err
+	}
+
+	if len(salt) != uberSaltLen {
+		return errors.New("got invalid salt length")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read entries: %w", err)
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	key := pkcs12KDF(sha1.New, bmp, salt, iterations, pkcs12KeyID, uberKeyLen)
+	defer zeroing(key)
+
+	block, err := twofish.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("create cipher: %w", err)
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return errors.New("got invalid encrypted data length")
+	}
+
+	iv := pkcs12KDF(sha1.New, bmp, salt, iterations, pkcs12IVID, block.BlockSize())
+	plain := make([]byte, len(data))
+
+	defer zeroing(plain)
+
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	plain, err = unpad(plain, block.BlockSize())
+	if err != nil || len(plain) < sha1.Size {
+		return fmt.Errorf("decrypt keystore: %w", ErrIncorrectPassword)
+	}
+
+	store, expectedDigest := plain[:len(plain)-sha1.Size], plain[len(plain)-sha1.Size:]
+
+	if digest := sha1.Sum(store); !hmac.Equal(digest[:], expectedDigest) {
+		return fmt.Errorf("got invalid digest: %w", ErrIncorrectPassword)
+	}
+
+	entries, err := readBKSEntries(store)
+	if err != nil {
+		return err
+	}
+
+	return ks.addBKSEntries(entries, password, keyPasswords)
+}
+
+// UnsealEntry decrypts SealedEntry by the alias with the key password and replaces it with PrivateKeyEntry
+// encrypted with the same password or with SecretKeyEntry.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) UnsealEntry(alias string, password []byte) error {
+	se, err := ks.GetSealedEntry(alias)
+	if err != nil {
+		return err
+	}
+
+	entry, err := unsealBKSKey(se, password)
+	if err != nil {
+		return fmt.Errorf("unseal entry: %w", err)
+	}
+
+	switch typedEntry := entry.(type) {
+	case PrivateKeyEntry:
+		defer zeroing(typedEntry.PrivateKey)
+
+		return ks.SetPrivateKeyEntry(alias, typedEntry, password)
+	default:
+		ks.m[ks.convertAlias(alias)] = typedEntry
+
+		return nil
+	}
+}
+
+type bksEntry struct {
+	alias string
+	entry interface{}
+}
+
+func readBKSSaltAndIterations(d decoder) ([]byte, int, error) {
+	saltLen, err := d.readUint32()
+	if err != nil {
+		return nil, 0, fmt.Errorf("read salt length: %w", err)
+	}
+
+	if saltLen == 0 || saltLen > 1024 { //nolint:gomnd,mnd
+		return nil, 0, errors.New("got invalid salt length")
+	}
+
+	salt, err := d.readBytes(saltLen)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read salt: %w", err)
+	}
+
+	iterations, err := d.readUint32()
+	if err != nil {
+		return nil, 0, fmt.Errorf("read iteration count: %w", err)
+	}
+
+	if iterations == 0 || iterations > bksMaxIterations {
+		return nil, 0, errors.New("got invalid iteration count")
+	}
+
+	return salt, int(iterations), nil
+}
+
+// readBKSEntries reads entries up to the null entry which must end the store.
+func readBKSEntries(store []byte) ([]bksEntry, error) {
+	r := bytes.NewReader(store)
+	d := decoder{r: r, h: sha1.New()}
+
+	var entries []bksEntry
+
+	for i := 0; ; i++ {
+		entryType, err := d.readBytes(1)
+		if err != nil {
+			return nil, fmt.Errorf("read %d entry type: %w", i, err)
+		}
+
+		if entryType[0] == bksEntryNull {
+			break
+		}
+
+		alias, entry, err := readBKSEntry(d, entryType[0])
+		if err != nil {
+			return nil, fmt.Errorf("read %d entry: %w", i, err)
+		}
+
+		entries = append(entries, bksEntry{alias: alias, entry: entry})
+	}
+
+	if r.Len() > 0 {
+		return nil, errors.New("got extra data after entries")
+	}
+
+	return entries, nil
+}
+
+func readBKSEntry(d decoder, entryType byte) (string, interface{}, error) {
+	alias, err := d.readString()
+	if err != nil {
+		return "", nil, fmt.Errorf("read alias: %w", err)
+	}
+
+	creationTimeStamp, err := d.readUint64()
+	if err != nil {
+		return "", nil, fmt.Errorf("read creation timestamp: %w", err)
+	}
+
+	creationTime := time.UnixMilli(int64(creationTimeStamp)) //nolint:gosec
+
+	chainLen, err := d.readUint32()
+	if err != nil {
+		return "", nil, fmt.Errorf("read number of certificates: %w", err)
+	}
+
+	var chain []Certificate
+
+	for i := range chainLen {
+		cert, err := d.readCertificate(version02)
+		if err != nil {
+			return "", nil, fmt.Errorf("read %d certificate: %w", i, err)
+		}
+
+		chain = append(chain, cert)
+	}
+
+	switch entryType {
+	case bksEntryCertificate:
+		cert, err := d.readCertificate(version02)
+		if err != nil {
+			return "", nil, fmt.Errorf("read certificate: %w", err)
+		}
+
+		return alias, TrustedCertificateEntry{CreationTime: creationTime, Certificate: cert}, nil
+	case bksEntryKey:
+		entry, err := readBKSKey(d, creationTime, chain)
+		if err != nil {
+			return "", nil, fmt.Errorf("read key: %w", err)
+		}
+
+		return alias, entry, nil
+	case bksEntrySecret, bksEntrySealed:
+		length, err := d.readUint32()
+		if err != nil {
+			return "", nil, fmt.Errorf("read length: %w", err)
+		}
+
+		content, err := d.readBytes(length)
+		if err != nil {
+			return "", nil, fmt.Errorf("read content: %w", err)
+		}
+
+		if entryType == bksEntrySecret {
+			return alias, SecretKeyEntry{CreationTime: creationTime, Key: content, CertificateChain: chain}, nil
+		}
+
+		return alias, SealedEntry{CreationTime: creationTime, Content: content, CertificateChain: chain}, nil
+	default:
+		return "", nil, fmt.Errorf("got unknown entry type %d", entryType)
+	}
+}
+
+// readBKSKey reads key and returns PrivateKeyEntry with not encrypted private key or SecretKeyEntry.
+func readBKSKey(d decoder, creationTime time.Time, chain []Certificate) (interface{}, error) {
+	keyType, err := d.readBytes(1)
+	if err != nil {
+		return nil, fmt.Errorf("read type: %w", err)
+	}
+
+	format, err := d.readString()
+	if err != nil {
+		return nil, fmt.Errorf("read format: %w", err)
+	}
+
+	algorithm, err := d.readString()
+	if err != nil {
+		return nil, fmt.Errorf("read algorithm: %w", err)
+	}
+
+	length, err := d.readUint32()
+	if err != nil {
+		return nil, fmt.Errorf("read length: %w", err)
+	}
+
+	encoded, err := d.readBytes(length)
+	if err != nil {
+		return nil, fmt.Errorf("read encoded key: %w", err)
+	}
+
+	switch keyType[0] {
+	case bksKeyPrivate:
+		if format != "PKCS#8" && format != "PKCS8" {
+			zeroing(encoded)
+
+			return nil, fmt.Errorf("got unsupported private key format %q", format)
+		}
+
+		return PrivateKeyEntry{CreationTime: creationTime, PrivateKey: encoded, CertificateChain: chain}, nil
+	case bksKeySecret:
+		return SecretKeyEntry{
+			CreationTime:     creationTime,
+			Algorithm:        algorithm,
+			Format:           format,
+			Key:              encoded,
+			CertificateChain: chain,
+		}, nil
+	case bksKeyPublic:
+		return nil, errors.New("got unsupported public key entry")
+	default:
+		return nil, fmt.Errorf("got unknown key type %d", keyType[0])
+	}
+}
+
+// unsealBKSKey decrypts key sealed with PBEWithSHAAnd3-KeyTripleDES-CBC.
+func unsealBKSKey(se SealedEntry, password []byte) (interface{}, error) {
+	d := decoder{r: bytes.NewReader(se.Content), h: sha1.New()}
+
+	salt, iterations, err := readBKSSaltAndIterations(d)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := io.ReadAll(d.r)
+	if err != nil {
+		return nil, fmt.Errorf("read encrypted key: %w", err)
+	}
+
+	if len(encrypted) == 0 || len(encrypted)%des.BlockSize != 0 {
+		return nil, errors.New("got invalid encrypted key length")
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	key := pkcs12KDF(sha1.New, bmp, salt, iterations, pkcs12KeyID, 24) //nolint:gomnd,mnd
+	defer zeroing(key)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	iv := pkcs12KDF(sha1.New, bmp, salt, iterations, pkcs12IVID, des.BlockSize)
+	plain := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, encrypted)
+
+	unpadded, err := unpad(plain, des.BlockSize)
+	if err != nil {
+		zeroing(plain)
+
+		return nil, fmt.Errorf("decrypt key: %w", ErrIncorrectPassword)
+	}
+
+	entry, err := readBKSKey(decoder{r: bytes.NewReader(unpadded), h: sha1.New()}, se.CreationTime, se.CertificateChain)
+
+	zeroing(plain)
+
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+
+	return entry, nil
+}
+
+// addBKSEntries adds entries into the keystore only if all of them can be added.
+func (ks KeyStore) addBKSEntries(entries []bksEntry, password []byte, keyPasswords KeyPasswordResolver) error {
+	defer func() {
+		for _, e := range entries {
+			if pke, ok := e.entry.(PrivateKeyEntry); ok {
+				zeroing(pke.PrivateKey)
+			}
+		}
+	}()
+
+	staging := ks
+	staging.m = make(map[string]interface{}, len(entries))
+
+	for _, e := range entries {
+		pke, ok := e.entry.(PrivateKeyEntry)
+		if !ok {
+			staging.m[ks.convertAlias(e.alias)] = e.entry
+
+			continue
+		}
+
+		var source PasswordSource
+		if keyPasswords != nil {
+			source = keyPasswords(e.alias)
+		}
+
+		var err error
+
+		if source == nil {
+			err = staging.SetPrivateKeyEntry(e.alias, pke, password)
+		} else {
+			err = staging.SetPrivateKeyEntryWithSource(e.alias, pke, source)
+		}
+
+		if err != nil {
+			return fmt.Errorf("set private key entry %q: %w", e.alias, err)
+		}
+	}
+
+	for alias, entry := range staging.m {
+		ks.m[alias] = entry
+	}
+
+	return nil
+}
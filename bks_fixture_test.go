@@ -0,0 +1,68 @@
+//go:build bouncycastle
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadBouncyCastleFixtures loads keystores written by BouncyCastle from testdata/keystore.p12:
+//
+//	keytool -importkeystore -srckeystore testdata/keystore.p12 -srcstoretype PKCS12 -srcstorepass password \
+//	  -destkeystore testdata/bc/keystore_v2.bks -deststoretype BKS -deststorepass password -destkeypass password \
+//	  -providerclass org.bouncycastle.jce.provider.BouncyCastleProvider -providerpath bcprov.jar
+//
+// with -deststoretype BKS-V1 for keystore_v1.bks and UBER for keystore.uber. The test is built with
+// bouncycastle tag only and fails if any fixture is missing: go test -tags bouncycastle -run BouncyCastle.
+func TestLoadBouncyCastleFixtures(t *testing.T) {
+	table := []struct {
+		filename string
+		load     func(ks KeyStore, r io.Reader, password []byte) error
+	}{
+		{"./testdata/bc/keystore_v1.bks", func(ks KeyStore, r io.Reader, password []byte) error {
+			return ks.LoadBKS(r, password, nil)
+		}},
+		{"./testdata/bc/keystore_v2.bks", func(ks KeyStore, r io.Reader, password []byte) error {
+			return ks.LoadBKS(r, password, nil)
+		}},
+		{"./testdata/bc/keystore.uber", func(ks KeyStore, r io.Reader, password []byte) error {
+			return ks.LoadUBER(r, password, nil)
+		}},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.filename, func(t *testing.T) {
+			data, err := os.ReadFile(tt.filename)
+			require.NoError(t, err, "fixture must be generated by BouncyCastle")
+
+			ks := New()
+			require.NoError(t, tt.load(ks, bytes.NewReader(data), []byte("password")))
+			assert.Equal(t, []string{"server"}, ks.Aliases())
+
+			require.ErrorIs(t, tt.load(New(), bytes.NewReader(data), []byte("wrong")), ErrIncorrectPassword)
+
+			if ks.IsSealedEntry("server") {
+				require.NoError(t, ks.UnsealEntry("server", []byte("password")))
+			}
+
+			pke, err := ks.GetPrivateKeyEntry("server", []byte("password"))
+			require.NoError(t, err)
+			require.Len(t, pke.CertificateChain, 2)
+
+			leaf, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+			require.NoError(t, err)
+			assert.Equal(t, "CN=localhost", leaf.Subject.String())
+
+			key, err := parsePrivateKey(pke.PrivateKey)
+			require.NoError(t, err)
+			assert.True(t, publicKeysEqual(leaf.PublicKey, key.Public()))
+		})
+	}
+}
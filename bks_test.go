@@ -0,0 +1,287 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/twofish"
+)
+
+const testBKSIterations = 1024
+
+var testBKSCreationTime = time.UnixMilli(1603999512000)
+
+func TestLoadBKS(t *testing.T) {
+	for _, version := range []uint32{bksVersion1, bksVersion2} {
+		data := marshalTestBKS(t, version, []byte("password"), newTestBKSStore(t))
+
+		ks := New(WithOrderedAliases())
+		require.NoError(t, ks.LoadBKS(bytes.NewReader(data), []byte("password"), nil))
+		assertTestBKSEntries(t, ks)
+
+		err := New().LoadBKS(bytes.NewReader(data), []byte("wrong"), nil)
+		require.ErrorIs(t, err, ErrIncorrectPassword)
+	}
+}
+
+func TestLoadBKSWithoutPassword(t *testing.T) {
+	store := newTestBKSStore(t)
+	data := marshalTestBKS(t, bksVersion2, []byte("password"), store)
+
+	ks := New()
+	require.NoError(t, ks.LoadBKS(bytes.NewReader(data), nil, KeyPasswords(nil,
+		PasswordFunc(func() ([]byte, error) { return []byte("keypassword"), nil }))))
+
+	_, err := ks.GetPrivateKeyEntry("server", []byte("keypassword"))
+	require.NoError(t, err)
+
+	err = New().LoadBKS(bytes.NewReader(append(data[:len(data)-sha1.Size-1], 1)), nil, nil)
+	require.Error(t, err)
+}
+
+func TestLoadBKSKeepsKeyStoreOnError(t *testing.T) {
+	data := marshalTestBKS(t, bksVersion2, []byte("password"), newTestBKSStore(t))
+
+	errSource := errors.New("source")
+
+	ks := New()
+	err := ks.LoadBKS(bytes.NewReader(data), []byte("password"), KeyPasswords(nil,
+		PasswordFunc(func() ([]byte, error) { return nil, errSource })))
+	require.ErrorIs(t, err, errSource)
+	assert.Empty(t, ks.Aliases())
+}
+
+func TestLoadUBER(t *testing.T) {
+	data := marshalTestUBER(t, []byte("password"), newTestBKSStore(t))
+
+	ks := New(WithOrderedAliases())
+	require.NoError(t, ks.LoadUBER(bytes.NewReader(data), []byte("password"), nil))
+	assertTestBKSEntries(t, ks)
+
+	err := New().LoadUBER(bytes.NewReader(data), []byte("wrong"), nil)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func TestUnsealEntry(t *testing.T) {
+	data := marshalTestBKS(t, bksVersion2, []byte("password"), newTestBKSStore(t))
+
+	ks := New()
+	require.NoError(t, ks.LoadBKS(bytes.NewReader(data), []byte("password"), nil))
+
+	err := ks.UnsealEntry("sealed", []byte("wrong"))
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	require.NoError(t, ks.UnsealEntry("sealed", []byte("keypassword")))
+	assert.False(t, ks.IsSealedEntry("sealed"))
+
+	pke, err := ks.GetPrivateKeyEntry("sealed", []byte("keypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+	assert.Equal(t, []Certificate{{Type: "X.509", Content: readCertificate(t)}}, pke.CertificateChain)
+
+	require.ErrorIs(t, ks.UnsealEntry("server", []byte("password")), ErrWrongEntryType)
+}
+
+func TestBKSKeyDerivation(t *testing.T) {
+	// Expected keys are derived with openssl kdf PKCS12KDF from the same password, salt and iteration count.
+	table := []struct {
+		id, size, iterations int
+		expected             string
+	}{
+		{pkcs12KeyID, 24, 1024, "fed71deaf60aafe7b00fdc6b111544d04b2f6dfcabc572dc"},
+		{pkcs12IVID, 8, 1024, "8a66e6beab95b8f4"},
+		{pkcs12MACID, sha1.Size, 1024, "8c8e639b872fd08ce28823f55ce375ccfb3b3828"},
+		{pkcs12MACID, bksVersion1MACKeyLen, 1024, "8c8e"},
+		{pkcs12KeyID, uberKeyLen, 2048, "4e3093e2807537d3784d547d4381009ba4cde935b8574c7195c002435cad37d1"},
+		{pkcs12IVID, 16, 2048, "da5f70795c63f0eaecd384c9fa97bcf9"},
+	}
+
+	salt := make([]byte, 20)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+
+	for _, tt := range table {
+		key := pkcs12KDF(sha1.New, bmpPassword([]byte("password")), salt, tt.iterations, tt.id, tt.size)
+		assert.Equal(t, tt.expected, hex.EncodeToString(key))
+	}
+}
+
+func assertTestBKSEntries(t *testing.T, ks KeyStore) {
+	t.Helper()
+
+	assert.Equal(t, []string{"aes", "ca", "opaque", "sealed", "server"}, ks.Aliases())
+
+	tce, err := ks.GetTrustedCertificateEntry("ca")
+	require.NoError(t, err)
+	assert.Equal(t, readCertificate(t), tce.Certificate.Content)
+	assert.True(t, testBKSCreationTime.Equal(tce.CreationTime))
+
+	pke, err := ks.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+	assert.Len(t, pke.CertificateChain, 1)
+
+	ske, err := ks.GetSecretKeyEntry("aes")
+	require.NoError(t, err)
+	assert.Equal(t, SecretKeyEntry{
+		CreationTime: testBKSCreationTime,
+		Algorithm:    "AES",
+		Format:       "RAW",
+		Key:          bytes.Repeat([]byte{1}, 16),
+	}, ske)
+
+	ske, err = ks.GetSecretKeyEntry("opaque")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, ske.Key)
+	assert.Empty(t, ske.Algorithm)
+
+	se, err := ks.GetSealedEntry("sealed")
+	require.NoError(t, err)
+	assert.Len(t, se.CertificateChain, 1)
+
+	infos, err := ks.List()
+	require.NoError(t, err)
+	assert.Equal(t, sealedEntryType, infos[3].EntryType)
+}
+
+func newTestBKSStore(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	cert := readCertificate(t)
+	key := readPrivateKey(t)
+
+	writeTestBKSEntryHeader(&buf, bksEntryCertificate, "ca", nil)
+	writeTestBKSCertificate(&buf, cert)
+
+	writeTestBKSEntryHeader(&buf, bksEntryKey, "server", [][]byte{cert})
+	writeTestBKSKey(&buf, bksKeyPrivate, "PKCS#8", "EC", key)
+
+	writeTestBKSEntryHeader(&buf, bksEntryKey, "aes", nil)
+	writeTestBKSKey(&buf, bksKeySecret, "RAW", "AES", bytes.Repeat([]byte{1}, 16))
+
+	writeTestBKSEntryHeader(&buf, bksEntrySecret, "opaque", nil)
+	writeTestBKSBytes(&buf, []byte{1, 2, 3})
+
+	var sealed bytes.Buffer
+
+	writeTestBKSKey(&sealed, bksKeyPrivate, "PKCS#8", "EC", key)
+	writeTestBKSEntryHeader(&buf, bksEntrySealed, "sealed", [][]byte{cert})
+	writeTestBKSBytes(&buf, sealTestBKSKey(t, []byte("keypassword"), sealed.Bytes()))
+
+	buf.WriteByte(bksEntryNull)
+
+	return buf.Bytes()
+}
+
+func writeTestBKSEntryHeader(buf *bytes.Buffer, entryType byte, alias string, chain [][]byte) {
+	buf.WriteByte(entryType)
+	writeTestBKSString(buf, alias)
+	_ = binary.Write(buf, binary.BigEndian, testBKSCreationTime.UnixMilli())
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(chain)))
+
+	for _, cert := range chain {
+		writeTestBKSCertificate(buf, cert)
+	}
+}
+
+func writeTestBKSCertificate(buf *bytes.Buffer, cert []byte) {
+	writeTestBKSString(buf, "X.509")
+	writeTestBKSBytes(buf, cert)
+}
+
+func writeTestBKSKey(buf *bytes.Buffer, keyType byte, format, algorithm string, encoded []byte) {
+	buf.WriteByte(keyType)
+	writeTestBKSString(buf, format)
+	writeTestBKSString(buf, algorithm)
+	writeTestBKSBytes(buf, encoded)
+}
+
+func writeTestBKSString(buf *bytes.Buffer, s string) {
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+func writeTestBKSBytes(buf *bytes.Buffer, b []byte) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(b)))
+	buf.Write(b)
+}
+
+func writeTestBKSSaltAndIterations(buf *bytes.Buffer, salt []byte) {
+	writeTestBKSBytes(buf, salt)
+	_ = binary.Write(buf, binary.BigEndian, uint32(testBKSIterations))
+}
+
+func marshalTestBKS(t *testing.T, version uint32, password, store []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	salt := bytes.Repeat([]byte{2}, 20)
+	_ = binary.Write(&buf, binary.BigEndian, version)
+	writeTestBKSSaltAndIterations(&buf, salt)
+	buf.Write(store)
+
+	macKeyLen := sha1.Size
+	if version == bksVersion1 {
+		macKeyLen = bksVersion1MACKeyLen
+	}
+
+	key := pkcs12KDF(sha1.New, bmpPassword(password), salt, testBKSIterations, pkcs12MACID, macKeyLen)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(store)
+
+	return mac.Sum(buf.Bytes())
+}
+
+func marshalTestUBER(t *testing.T, password, store []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	salt := bytes.Repeat([]byte{3}, uberSaltLen)
+	_ = binary.Write(&buf, binary.BigEndian, uint32(bksVersion2))
+	writeTestBKSSaltAndIterations(&buf, salt)
+
+	bmp := bmpPassword(password)
+	block, err := twofish.NewCipher(pkcs12KDF(sha1.New, bmp, salt, testBKSIterations, pkcs12KeyID, uberKeyLen))
+	require.NoError(t, err)
+
+	digest := sha1.Sum(store)
+	plain := pad(append(append([]byte{}, store...), digest[:]...), block.BlockSize())
+	iv := pkcs12KDF(sha1.New, bmp, salt, testBKSIterations, pkcs12IVID, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(plain, plain)
+
+	return append(buf.Bytes(), plain...)
+}
+
+func sealTestBKSKey(t *testing.T, password, key []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	salt := bytes.Repeat([]byte{4}, 20)
+	writeTestBKSSaltAndIterations(&buf, salt)
+
+	bmp := bmpPassword(password)
+	block, err := des.NewTripleDESCipher(pkcs12KDF(sha1.New, bmp, salt, testBKSIterations, pkcs12KeyID, 24))
+	require.NoError(t, err)
+
+	plain := pad(key, des.BlockSize)
+	iv := pkcs12KDF(sha1.New, bmp, salt, testBKSIterations, pkcs12IVID, des.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(plain, plain)
+
+	return append(buf.Bytes(), plain...)
+}
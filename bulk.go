@@ -0,0 +1,164 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UnlockOptions tells UnlockPrivateKeyEntries which passwords to try for every private key entry.
+// Passwords are tried in the order: Passwords, Resolver, Candidates.
+type UnlockOptions struct {
+	// Passwords are key passwords by alias. Aliases are converted the same way as in the keystore.
+	Passwords map[string][]byte
+	// Resolver returns source of the key password for the alias. It may return nil if it does not know one.
+	Resolver KeyPasswordResolver
+	// Candidates are passwords tried for every entry, e.g. the store password.
+	Candidates [][]byte
+}
+
+// UnlockResult reports result of UnlockPrivateKeyEntries.
+type UnlockResult struct {
+	// Entries are decrypted private key entries by alias.
+	Entries map[string]PrivateKeyEntry
+	// Passwords are key passwords which decrypted the entries by alias.
+	Passwords map[string][]byte
+	// Locked are private key entries which could not be decrypted in the order of Aliases.
+	Locked []LockedEntry
+}
+
+// LockedEntry is a private key entry which could not be decrypted.
+type LockedEntry struct {
+	Alias string
+	// Err is ErrIncorrectPassword if none of the passwords matched or the last error otherwise.
+	Err error
+}
+
+// LockedAliases returns aliases of the locked entries.
+func (r UnlockResult) LockedAliases() []string {
+	aliases := make([]string, len(r.Locked))
+	for i, locked := range r.Locked {
+		aliases[i] = locked.Alias
+	}
+
+	return aliases
+}
+
+// Zeroing fills decrypted private keys and passwords with zeros, see PrivateKeyEntry.Destroy.
+func (r UnlockResult) Zeroing() {
+	for _, pke := range r.Entries {
+		pke.Destroy()
+	}
+
+	for _, password := range r.Passwords {
+		zeroing(password)
+	}
+}
+
+// UnlockPrivateKeyEntries decrypts every private key entry of the keystore trying passwords from opts.
+// Entries which could not be decrypted are reported in the result and do not stop the others.
+// It is strongly recommended to call UnlockResult.Zeroing after usage.
+func (ks KeyStore) UnlockPrivateKeyEntries(opts UnlockOptions) UnlockResult {
+	passwords := make(map[string][]byte, len(opts.Passwords))
+	for alias, password := range opts.Passwords {
+		passwords[ks.convertAlias(alias)] = password
+	}
+
+	result := UnlockResult{
+		Entries:   make(map[string]PrivateKeyEntry),
+		Passwords: make(map[string][]byte),
+	}
+
+	for _, alias := range ks.Aliases() {
+		if !ks.IsPrivateKeyEntry(alias) {
+			continue
+		}
+
+		pke, password, err := ks.unlockPrivateKeyEntry(alias, passwords[alias], opts)
+		if err != nil {
+			result.Locked = append(result.Locked, LockedEntry{Alias: alias, Err: err})
+
+			continue
+		}
+
+		result.Entries[alias] = pke
+		result.Passwords[alias] = password
+	}
+
+	return result
+}
+
+// ReencryptPrivateKeyEntries decrypts every private key entry of the keystore trying passwords from opts
+// and encrypts it again with the password from newPasswords. Locked entries are left as is and are the only
+// entries reported in the result. The keystore is changed only if every unlocked entry is encrypted again.
+// ErrEntryNotFound is returned if newPasswords returns nil source for the alias.
+func (ks KeyStore) ReencryptPrivateKeyEntries(opts UnlockOptions, newPasswords KeyPasswordResolver) (
+	UnlockResult, error,
+) {
+	result := ks.UnlockPrivateKeyEntries(opts)
+	defer result.Zeroing()
+
+	staging := ks
+	staging.m = make(map[string]interface{}, len(result.Entries))
+
+	for _, alias := range ks.Aliases() {
+		pke, ok := result.Entries[alias]
+		if !ok {
+			continue
+		}
+
+		source := newPasswords(alias)
+		if source == nil {
+			return UnlockResult{}, fmt.Errorf("get new password of %q: %w", alias, ErrEntryNotFound)
+		}
+
+		if err := staging.SetPrivateKeyEntryWithSource(alias, pke, source); err != nil {
+			return UnlockResult{}, fmt.Errorf("reencrypt %q: %w", alias, err)
+		}
+	}
+
+	for alias, entry := range staging.m {
+		ks.m[alias] = entry
+	}
+
+	return UnlockResult{Locked: result.Locked}, nil
+}
+
+func (ks KeyStore) unlockPrivateKeyEntry(alias string, password []byte, opts UnlockOptions) (
+	PrivateKeyEntry, []byte, error,
+) {
+	var candidates [][]byte
+
+	err := ErrIncorrectPassword
+
+	if password != nil {
+		candidates = append(candidates, password)
+	}
+
+	if opts.Resolver != nil {
+		if source := opts.Resolver(alias); source != nil {
+			resolved, sourceErr := source.Password()
+			if sourceErr != nil {
+				err = fmt.Errorf("get password: %w", sourceErr)
+			} else {
+				defer zeroing(resolved)
+
+				candidates = append(candidates, resolved)
+			}
+		}
+	}
+
+	candidates = append(candidates, opts.Candidates...)
+
+	for _, candidate := range candidates {
+		pke, decryptErr := ks.GetPrivateKeyEntry(alias, candidate)
+		if decryptErr == nil {
+			return pke, append([]byte{}, candidate...), nil
+		}
+
+		if !errors.Is(decryptErr, ErrIncorrectPassword) {
+			err = decryptErr
+		}
+	}
+
+	return PrivateKeyEntry{}, nil, err
+}
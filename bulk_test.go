@@ -0,0 +1,86 @@
+package keystore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bulkTestKeyPasswords = map[string]string{
+	"a": "password-a",
+	"b": "password-b",
+	"c": "password-c",
+	"d": "password-d",
+	"e": "password-e",
+}
+
+func TestUnlockPrivateKeyEntries(t *testing.T) {
+	ks := newTestKeyStore(t, bulkTestKeyPasswords, "tce")
+
+	result := ks.UnlockPrivateKeyEntries(UnlockOptions{
+		Passwords: map[string][]byte{"A": []byte("password-a")},
+		Resolver: KeyPasswords(map[string]PasswordSource{
+			"b": PasswordFunc(func() ([]byte, error) { return []byte("password-b"), nil }),
+			"d": PasswordFunc(func() ([]byte, error) { return nil, errors.New("source failed") }),
+		}, nil),
+		Candidates: [][]byte{[]byte("wrong"), []byte("password-c")},
+	})
+	defer result.Zeroing()
+
+	assert.Len(t, result.Entries, 3)
+	assert.Equal(t, readPrivateKey(t), result.Entries["a"].PrivateKey)
+	assert.Equal(t, []byte("password-a"), result.Passwords["a"])
+	assert.Equal(t, []byte("password-b"), result.Passwords["b"])
+	assert.Equal(t, []byte("password-c"), result.Passwords["c"])
+
+	assert.Equal(t, []string{"d", "e"}, result.LockedAliases())
+	require.Error(t, result.Locked[0].Err)
+	require.NotErrorIs(t, result.Locked[0].Err, ErrIncorrectPassword)
+	require.ErrorIs(t, result.Locked[1].Err, ErrIncorrectPassword)
+}
+
+func TestReencryptPrivateKeyEntries(t *testing.T) {
+	ks := newTestKeyStore(t, bulkTestKeyPasswords, "tce")
+
+	newPassword := PasswordFunc(func() ([]byte, error) { return []byte("new-password"), nil })
+
+	result, err := ks.ReencryptPrivateKeyEntries(UnlockOptions{
+		Candidates: [][]byte{[]byte("password-a"), []byte("password-b")},
+	}, KeyPasswords(nil, newPassword))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "e"}, result.LockedAliases())
+
+	for _, alias := range []string{"a", "b"} {
+		pke, err := ks.GetPrivateKeyEntry(alias, []byte("new-password"))
+		require.NoError(t, err)
+		assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+	}
+
+	_, err = ks.GetPrivateKeyEntry("c", []byte("password-c"))
+	require.NoError(t, err)
+
+	_, err = ks.ReencryptPrivateKeyEntries(UnlockOptions{
+		Candidates: [][]byte{[]byte("password-c")},
+	}, KeyPasswords(nil, nil))
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestReencryptPrivateKeyEntriesFailure(t *testing.T) {
+	ks := newTestKeyStore(t, bulkTestKeyPasswords, "tce")
+
+	errSource := errors.New("source")
+
+	_, err := ks.ReencryptPrivateKeyEntries(UnlockOptions{
+		Candidates: [][]byte{[]byte("password-a"), []byte("password-b")},
+	}, KeyPasswords(map[string]PasswordSource{
+		"b": PasswordFunc(func() ([]byte, error) { return nil, errSource }),
+	}, PasswordFunc(func() ([]byte, error) { return []byte("new-password"), nil })))
+	require.ErrorIs(t, err, errSource)
+
+	for alias, password := range map[string]string{"a": "password-a", "b": "password-b"} {
+		_, err := ks.GetPrivateKeyEntry(alias, []byte(password))
+		require.NoError(t, err, "entry %q must keep the old password", alias)
+	}
+}
@@ -0,0 +1,143 @@
+package keystore
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	ccmBlockSize   = 16
+	ccmMinNonceLen = 7
+	ccmMaxNonceLen = 13
+	ccmMinTagLen   = 4
+	ccmMaxTagLen   = 16
+)
+
+var errMessageAuthentication = errors.New("message authentication failed")
+
+// ccmSeal encrypts and authenticates plaintext with AES in CCM mode of NIST SP 800-38C.
+func ccmSeal(block cipher.Block, nonce, plaintext, additionalData []byte, tagLen int) ([]byte, error) {
+	if err := checkCCMParameters(block, nonce, plaintext, tagLen); err != nil {
+		return nil, err
+	}
+
+	tag := ccmMAC(block, nonce, plaintext, additionalData, tagLen)
+
+	out := make([]byte, len(plaintext), len(plaintext)+tagLen)
+	ccmCTR(block, nonce, out, plaintext, tag)
+
+	return append(out, tag...), nil
+}
+
+// ccmOpen decrypts and authenticates ciphertext with AES in CCM mode of NIST SP 800-38C.
+func ccmOpen(block cipher.Block, nonce, ciphertext, additionalData []byte, tagLen int) ([]byte, error) {
+	if len(ciphertext) < tagLen {
+		return nil, errMessageAuthentication
+	}
+
+	if err := checkCCMParameters(block, nonce, ciphertext[:len(ciphertext)-tagLen], tagLen); err != nil {
+		return nil, err
+	}
+
+	tag := append([]byte{}, ciphertext[len(ciphertext)-tagLen:]...)
+	plaintext := make([]byte, len(ciphertext)-tagLen)
+	ccmCTR(block, nonce, plaintext, ciphertext[:len(plaintext)], tag)
+
+	if subtle.ConstantTimeCompare(tag, ccmMAC(block, nonce, plaintext, additionalData, tagLen)) != 1 {
+		zeroing(plaintext)
+
+		return nil, errMessageAuthentication
+	}
+
+	return plaintext, nil
+}
+
+func checkCCMParameters(block cipher.Block, nonce, plaintext []byte, tagLen int) error {
+	if block.BlockSize() != ccmBlockSize {
+		return errors.New("got unsupported block size")
+	}
+
+	if len(nonce) < ccmMinNonceLen || len(nonce) > ccmMaxNonceLen {
+		return errors.New("got invalid nonce length")
+	}
+
+	if tagLen < ccmMinTagLen || tagLen > ccmMaxTagLen || tagLen%2 != 0 {
+		return errors.New("got invalid tag length")
+	}
+
+	if l := 15 - len(nonce); l < 8 && uint64(len(plaintext)) >= 1<<(8*l) { //nolint:gomnd,mnd
+		return errors.New("got too long message")
+	}
+
+	return nil
+}
+
+// ccmMAC computes CBC-MAC of the formatted B blocks.
+func ccmMAC(block cipher.Block, nonce, plaintext, additionalData []byte, tagLen int) []byte {
+	l := 15 - len(nonce) //nolint:gomnd,mnd
+
+	var b [ccmBlockSize]byte
+
+	b[0] = byte((tagLen-2)/2<<3 | (l - 1)) //nolint:gomnd,mnd
+	if len(additionalData) > 0 {
+		b[0] |= 1 << 6 //nolint:gomnd,mnd
+	}
+
+	copy(b[1:], nonce)
+	putCCMLength(b[1+len(nonce):], uint64(len(plaintext)))
+
+	x := make([]byte, ccmBlockSize)
+	block.Encrypt(x, b[:])
+
+	if len(additionalData) > 0 {
+		var header []byte
+
+		if len(additionalData) < 0xff00 { //nolint:gomnd,mnd
+			header = binary.BigEndian.AppendUint16(nil, uint16(len(additionalData)))
+		} else {
+			header = binary.BigEndian.AppendUint32([]byte{0xff, 0xfe}, uint32(len(additionalData))) //nolint:gosec
+		}
+
+		ccmCBCMAC(block, x, append(header, additionalData...))
+	}
+
+	ccmCBCMAC(block, x, plaintext)
+
+	return x[:tagLen]
+}
+
+// ccmCBCMAC updates x with zero padded data.
+func ccmCBCMAC(block cipher.Block, x, data []byte) {
+	for len(data) > 0 {
+		n := min(len(data), ccmBlockSize)
+		subtle.XORBytes(x[:n], x[:n], data[:n])
+		block.Encrypt(x, x)
+		data = data[n:]
+	}
+}
+
+// ccmCTR encrypts src into dst with counter blocks starting from 1 and encrypts tag with counter block 0.
+func ccmCTR(block cipher.Block, nonce, dst, src, tag []byte) {
+	l := 15 - len(nonce) //nolint:gomnd,mnd
+
+	var counter [ccmBlockSize]byte
+
+	counter[0] = byte(l - 1)
+	copy(counter[1:], nonce)
+
+	s0 := make([]byte, ccmBlockSize)
+	block.Encrypt(s0, counter[:])
+	subtle.XORBytes(tag, tag, s0[:len(tag)])
+
+	counter[ccmBlockSize-1] = 1
+	cipher.NewCTR(block, counter[:]).XORKeyStream(dst, src)
+}
+
+func putCCMLength(b []byte, length uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(length)
+		length >>= 8
+	}
+}
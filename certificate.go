@@ -0,0 +1,156 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// X509CertificateType is the type Java gives X.509 certificates. Store writes it for "X509" too
+// and Load gives it to certificates of version 1 keystores.
+const X509CertificateType = "X.509"
+
+// CertificateParser parses certificate content of a type, see RegisterCertificateParser.
+type CertificateParser func(content []byte) (interface{}, error)
+
+var certificateParsers = struct {
+	sync.RWMutex
+	m map[string]CertificateParser
+}{m: make(map[string]CertificateParser)}
+
+// RegisterCertificateParser registers parser of certificates of the type. Store checks content of such
+// certificates with the parser and Certificate.Parse returns what it parses. Certificates of types without
+// a parser are stored as is. X.509 certificates are always
+// parsed with x509.ParseCertificate, the parser registered for them is ignored.
+func RegisterCertificateParser(certType string, parser CertificateParser) {
+	certificateParsers.Lock()
+	defer certificateParsers.Unlock()
+
+	certificateParsers.m[certType] = parser
+}
+
+func certificateParser(certType string) (CertificateParser, bool) {
+	if isX509CertificateType(certType) {
+		return func(content []byte) (interface{}, error) { return x509.ParseCertificate(content) }, true
+	}
+
+	certificateParsers.RLock()
+	defer certificateParsers.RUnlock()
+
+	parser, ok := certificateParsers.m[certType]
+
+	return parser, ok
+}
+
+// Parse parses the certificate content with the parser of its type. X.509 certificates are parsed
+// to *x509.Certificate. ErrUnsupportedCertificateType is returned if no parser is registered for the type.
+func (c Certificate) Parse() (interface{}, error) {
+	parser, ok := certificateParser(c.Type)
+	if !ok {
+		return nil, fmt.Errorf("parse %q certificate: %w", c.Type, ErrUnsupportedCertificateType)
+	}
+
+	parsed, err := parser(c.Content)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q certificate: %w", c.Type, err)
+	}
+
+	return parsed, nil
+}
+
+// x509CertificateDER is the outer structure of X.509 certificate.
+type x509CertificateDER struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// normalize returns the certificate with Java type name checking its content is DER of that type.
+// X.509 certificates are checked by their outer structure only, so certificates Java accepts and
+// x509.ParseCertificate does not are still valid. Other types are checked with registered parsers
+// and kept unchecked if there is none.
+func (c Certificate) normalize() (Certificate, error) {
+	if isX509CertificateType(c.Type) {
+		var der x509CertificateDER
+
+		rest, err := asn1.Unmarshal(c.Content, &der)
+		if err != nil {
+			return Certificate{}, fmt.Errorf("unmarshal X.509 certificate: %w", err)
+		}
+
+		if len(rest) > 0 {
+			return Certificate{}, errors.New("got extra data in X.509 certificate")
+		}
+
+		return Certificate{Type: X509CertificateType, Content: c.Content}, nil
+	}
+
+	parser, ok := certificateParser(c.Type)
+	if !ok {
+		return c, nil
+	}
+
+	if _, err := parser(c.Content); err != nil {
+		return Certificate{}, fmt.Errorf("parse %q certificate: %w", c.Type, err)
+	}
+
+	return c, nil
+}
+
+// isX509CertificateType tells if the type is X.509 either as Java names it or as older keystores do.
+func isX509CertificateType(certType string) bool {
+	return certType == X509CertificateType || certType == defaultCertificateType
+}
+
+// ParsePkiPath parses certification path in PkiPath encoding, the sequence of X.509 certificates ordered
+// from the root to the leaf the way Java CertPath.getEncoded("PkiPath") writes it. The returned chain is
+// ordered from the leaf to the root as the chain of PrivateKeyEntry.
+func ParsePkiPath(der []byte) ([]Certificate, error) {
+	var path []asn1.RawValue
+
+	rest, err := asn1.Unmarshal(der, &path)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal pki path: %w", err)
+	}
+
+	if len(rest) > 0 {
+		return nil, errors.New("got extra data in pki path")
+	}
+
+	chain := make([]Certificate, len(path))
+
+	for i, cert := range path {
+		c, err := Certificate{Type: X509CertificateType, Content: cert.FullBytes}.normalize()
+		if err != nil {
+			return nil, fmt.Errorf("check %d certificate: %w", i, err)
+		}
+
+		chain[len(path)-1-i] = c
+	}
+
+	return chain, nil
+}
+
+// MarshalPkiPath encodes chain ordered from the leaf to the root in PkiPath encoding, see ParsePkiPath.
+// ErrUnsupportedCertificateType is returned if any certificate is not X.509.
+func MarshalPkiPath(chain []Certificate) ([]byte, error) {
+	path := make([]asn1.RawValue, len(chain))
+
+	for i, cert := range chain {
+		if !isX509CertificateType(cert.Type) {
+			return nil, fmt.Errorf("check %d certificate type %q: %w", i, cert.Type, ErrUnsupportedCertificateType)
+		}
+
+		path[len(chain)-1-i] = asn1.RawValue{FullBytes: cert.Content}
+	}
+
+	der, err := asn1.Marshal(path)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pki path: %w", err)
+	}
+
+	return der, nil
+}
@@ -0,0 +1,153 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreNormalizesCertificateType(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, []byte("password")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X.509", Content: readCertificate(t)},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("password")))
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain("key")
+	require.NoError(t, err)
+	assert.Equal(t, "X509", chain[0].Type, "keystore must not be changed by Store")
+
+	loaded := New()
+	require.NoError(t, loaded.Load(&buf, []byte("password")))
+
+	chain, err = loaded.GetPrivateKeyEntryCertificateChain("key")
+	require.NoError(t, err)
+	assert.Equal(t, []Certificate{{Type: X509CertificateType, Content: readCertificate(t)}}, chain)
+
+	tce, err := loaded.GetTrustedCertificateEntry("cert")
+	require.NoError(t, err)
+	assert.Equal(t, Certificate{Type: X509CertificateType, Content: readCertificate(t)}, tce.Certificate)
+}
+
+func TestStoreInvalidCertificate(t *testing.T) {
+	cert := readCertificate(t)
+
+	for name, c := range map[string]Certificate{
+		"not der":       {Type: "X509", Content: []byte{1, 2, 3}},
+		"truncated":     {Type: "X.509", Content: cert[:len(cert)-1]},
+		"trailing data": {Type: "X509", Content: append(cloneBytes(cert), 0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ks := New()
+			require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+				CreationTime: time.Now(),
+				Certificate:  c,
+			}))
+
+			err := ks.Store(&bytes.Buffer{}, []byte("password"))
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, ErrUnsupportedCertificateType)
+		})
+	}
+}
+
+func TestStoreUnknownCertificateType(t *testing.T) {
+	c := Certificate{Type: "PGP", Content: []byte{1, 2, 3}}
+
+	ks := New()
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  c,
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("password")))
+
+	loaded := New()
+	require.NoError(t, loaded.Load(&buf, []byte("password")))
+
+	tce, err := loaded.GetTrustedCertificateEntry("cert")
+	require.NoError(t, err)
+	assert.Equal(t, c, tce.Certificate)
+}
+
+func TestRegisterCertificateParser(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	RegisterCertificateParser("TEST", func(content []byte) (interface{}, error) {
+		if !bytes.HasPrefix(content, []byte("test:")) {
+			return nil, errInvalid
+		}
+
+		return string(content[len("test:"):]), nil
+	})
+
+	parsed, err := Certificate{Type: "TEST", Content: []byte("test:content")}.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "content", parsed)
+
+	_, err = Certificate{Type: "TEST", Content: []byte("content")}.Parse()
+	require.ErrorIs(t, err, errInvalid)
+
+	_, err = Certificate{Type: "UNKNOWN", Content: []byte("content")}.Parse()
+	require.ErrorIs(t, err, ErrUnsupportedCertificateType)
+
+	parsed, err = Certificate{Type: "X509", Content: readCertificate(t)}.Parse()
+	require.NoError(t, err)
+	assert.IsType(t, &x509.Certificate{}, parsed)
+
+	ks := New()
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "TEST", Content: []byte("test:content")},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("password")))
+
+	ks.m["cert"] = TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "TEST", Content: []byte("content")},
+	}
+	require.ErrorIs(t, ks.Store(&bytes.Buffer{}, []byte("password")), errInvalid)
+}
+
+func TestPkiPath(t *testing.T) {
+	key := generateTestKey(t)
+	root, intermediate, leaf := issueTestChain(t, key.Public())
+
+	der, err := asn1.Marshal([]asn1.RawValue{{FullBytes: root.Raw}, {FullBytes: intermediate.Raw}, {FullBytes: leaf.Raw}})
+	require.NoError(t, err)
+
+	chain, err := ParsePkiPath(der)
+	require.NoError(t, err)
+	assert.Equal(t, []Certificate{
+		{Type: X509CertificateType, Content: leaf.Raw},
+		{Type: X509CertificateType, Content: intermediate.Raw},
+		{Type: X509CertificateType, Content: root.Raw},
+	}, chain)
+
+	marshaled, err := MarshalPkiPath(chain)
+	require.NoError(t, err)
+	assert.Equal(t, der, marshaled)
+
+	_, err = ParsePkiPath(append(cloneBytes(der), 0))
+	require.Error(t, err)
+
+	_, err = MarshalPkiPath([]Certificate{{Type: "PGP", Content: leaf.Raw}})
+	require.ErrorIs(t, err, ErrUnsupportedCertificateType)
+}
@@ -0,0 +1,190 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Encoding is an output encoding of generated objects.
+type Encoding int
+
+const (
+	EncodingPEM Encoding = iota
+	EncodingDER
+)
+
+var (
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+var extKeyUsageOIDs = map[x509.ExtKeyUsage]asn1.ObjectIdentifier{
+	x509.ExtKeyUsageAny:             {2, 5, 29, 37, 0},
+	x509.ExtKeyUsageServerAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	x509.ExtKeyUsageClientAuth:      {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	x509.ExtKeyUsageCodeSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	x509.ExtKeyUsageEmailProtection: {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	x509.ExtKeyUsageTimeStamping:    {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	x509.ExtKeyUsageOCSPSigning:     {1, 3, 6, 1, 5, 5, 7, 3, 9},
+}
+
+// CertificateRequestOptions describes PKCS#10 certificate signing request to create.
+type CertificateRequestOptions struct {
+	// Subject defaults to the subject of the first certificate in the entry chain.
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []*url.URL
+
+	// KeyUsage and ExtKeyUsage are requested through the extension request attribute if set.
+	KeyUsage        x509.KeyUsage
+	ExtKeyUsage     []x509.ExtKeyUsage
+	ExtraExtensions []pkix.Extension
+
+	// SignatureAlgorithm is chosen based on the key algorithm if unknown.
+	SignatureAlgorithm x509.SignatureAlgorithm
+	// Encoding of the returned request, PEM by default.
+	Encoding Encoding
+}
+
+// CreateCertificateRequest creates PKCS#10 certificate signing request signed with the private key
+// of PrivateKeyEntry from the keystore by the alias decrypted with the password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) CreateCertificateRequest(
+	alias string, password []byte, opts CertificateRequestOptions,
+) ([]byte, error) {
+	pke, err := ks.GetPrivateKeyEntry(alias, password)
+	if err != nil {
+		return nil, err
+	}
+
+	defer pke.Destroy()
+
+	key, err := parsePrivateKey(pke.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:            opts.Subject,
+		DNSNames:           opts.DNSNames,
+		IPAddresses:        opts.IPAddresses,
+		EmailAddresses:     opts.EmailAddresses,
+		URIs:               opts.URIs,
+		ExtraExtensions:    append([]pkix.Extension(nil), opts.ExtraExtensions...),
+		SignatureAlgorithm: opts.SignatureAlgorithm,
+	}
+
+	if len(opts.Subject.ToRDNSequence()) == 0 && len(pke.CertificateChain) > 0 {
+		cert, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+
+		template.RawSubject = cert.RawSubject
+	}
+
+	if opts.KeyUsage != 0 {
+		ext, err := marshalKeyUsage(opts.KeyUsage)
+		if err != nil {
+			return nil, err
+		}
+
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+
+	if len(opts.ExtKeyUsage) > 0 {
+		ext, err := marshalExtKeyUsage(opts.ExtKeyUsage)
+		if err != nil {
+			return nil, err
+		}
+
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+
+	der, err := x509.CreateCertificateRequest(ks.r, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate request: %w", err)
+	}
+
+	if opts.Encoding == EncodingDER {
+		return der, nil
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+func marshalKeyUsage(ku x509.KeyUsage) (pkix.Extension, error) {
+	var b [2]byte
+
+	b[0] = reverseBitsInAByte(byte(ku))
+	b[1] = reverseBitsInAByte(byte(ku >> 8)) //nolint:gomnd,mnd
+
+	l := 1
+	if b[1] != 0 {
+		l = 2
+	}
+
+	bitString := b[:l]
+
+	value, err := asn1.Marshal(asn1.BitString{Bytes: bitString, BitLength: asn1BitLength(bitString)})
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("marshal key usage: %w", err)
+	}
+
+	return pkix.Extension{Id: oidExtensionKeyUsage, Critical: true, Value: value}, nil
+}
+
+func marshalExtKeyUsage(ekus []x509.ExtKeyUsage) (pkix.Extension, error) {
+	oids := make([]asn1.ObjectIdentifier, 0, len(ekus))
+
+	for _, eku := range ekus {
+		oid, ok := extKeyUsageOIDs[eku]
+		if !ok {
+			return pkix.Extension{}, fmt.Errorf("got unsupported extended key usage %d", eku)
+		}
+
+		oids = append(oids, oid)
+	}
+
+	value, err := asn1.Marshal(oids)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("marshal extended key usage: %w", err)
+	}
+
+	return pkix.Extension{Id: oidExtensionExtendedKeyUsage, Value: value}, nil
+}
+
+func reverseBitsInAByte(in byte) byte {
+	b1 := in>>4 | in<<4           //nolint:gomnd,mnd
+	b2 := b1>>2&0x33 | b1<<2&0xcc //nolint:gomnd,mnd
+	b3 := b2>>1&0x55 | b2<<1&0xaa //nolint:gomnd,mnd
+
+	return b3
+}
+
+// asn1BitLength returns the bit-length of bitString by considering the most-significant bit in a byte to be
+// the "first" bit.
+func asn1BitLength(bitString []byte) int {
+	bitLen := len(bitString) * 8 //nolint:gomnd,mnd
+
+	for i := range bitString {
+		b := bitString[len(bitString)-i-1]
+
+		for bit := uint(0); bit < 8; bit++ { //nolint:gomnd,mnd
+			if (b>>bit)&1 == 1 {
+				return bitLen
+			}
+
+			bitLen--
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,114 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCertificateRequest(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.GenerateKeyPair("alias", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "localhost"},
+	}, password)
+	require.NoError(t, err)
+
+	csrPEM, err := ks.CreateCertificateRequest("alias", password, CertificateRequestOptions{
+		DNSNames:    []string{"localhost", "example.com"},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	require.NoError(t, err)
+
+	b, rest := pem.Decode(csrPEM)
+	require.NotNil(t, b)
+	assert.Empty(t, rest)
+	assert.Equal(t, "CERTIFICATE REQUEST", b.Type)
+
+	csr, err := x509.ParseCertificateRequest(b.Bytes)
+	require.NoError(t, err)
+	require.NoError(t, csr.CheckSignature())
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain("alias")
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+
+	assert.Equal(t, cert.PublicKey, csr.PublicKey)
+	assert.Equal(t, "CN=localhost", csr.Subject.String())
+	assert.Equal(t, []string{"localhost", "example.com"}, csr.DNSNames)
+
+	var (
+		keyUsage    asn1.BitString
+		extKeyUsage []asn1.ObjectIdentifier
+	)
+
+	for _, ext := range csr.Extensions {
+		switch {
+		case ext.Id.Equal(oidExtensionKeyUsage):
+			_, err := asn1.Unmarshal(ext.Value, &keyUsage)
+			require.NoError(t, err)
+		case ext.Id.Equal(oidExtensionExtendedKeyUsage):
+			_, err := asn1.Unmarshal(ext.Value, &extKeyUsage)
+			require.NoError(t, err)
+		}
+	}
+
+	assert.Equal(t, 5, keyUsage.BitLength)
+	assert.Equal(t, 1, keyUsage.At(0))
+	assert.Equal(t, 1, keyUsage.At(4))
+	assert.Equal(t, []asn1.ObjectIdentifier{extKeyUsageOIDs[x509.ExtKeyUsageServerAuth]}, extKeyUsage)
+}
+
+func TestCreateCertificateRequestDER(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.SetPrivateKeyEntry("alias", PrivateKeyEntry{PrivateKey: readPrivateKey(t)}, password)
+	require.NoError(t, err)
+
+	csrDER, err := ks.CreateCertificateRequest("alias", password, CertificateRequestOptions{
+		Subject:  pkix.Name{CommonName: "renewed", Organization: []string{"Example"}},
+		Encoding: EncodingDER,
+	})
+	require.NoError(t, err)
+
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	require.NoError(t, err)
+	require.NoError(t, csr.CheckSignature())
+	assert.Equal(t, "CN=renewed,O=Example", csr.Subject.String())
+
+	_, err = ks.CreateCertificateRequest("alias", []byte("wrong"), CertificateRequestOptions{})
+	require.Error(t, err)
+
+	_, err = ks.CreateCertificateRequest("missing", password, CertificateRequestOptions{})
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestCreateCertificateRequestKeepsExtraExtensions(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.SetPrivateKeyEntry("alias", PrivateKeyEntry{PrivateKey: readPrivateKey(t)}, password)
+	require.NoError(t, err)
+
+	extra := make([]pkix.Extension, 1, 2)
+	extra[0] = pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{5, 0}}
+
+	_, err = ks.CreateCertificateRequest("alias", password, CertificateRequestOptions{
+		Subject:         pkix.Name{CommonName: "localhost"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: extra,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pkix.Extension{}, extra[:2][1], "caller backing array must not be changed")
+}
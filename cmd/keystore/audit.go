@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+func audit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	keystoreFile := fs.String("keystore", "", "JKS keystore file")
+	wordlistFile := fs.String("wordlist", "", "file with one candidate password per line")
+	workers := fs.Int("workers", 0, "number of parallel workers, the number of CPUs by default")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *keystoreFile == "" || *wordlistFile == "" {
+		return errors.New("audit: -keystore and -wordlist are required")
+	}
+
+	content, err := os.ReadFile(*wordlistFile)
+	if err != nil {
+		return fmt.Errorf("audit: read wordlist: %w", err)
+	}
+
+	defer zeroing(content)
+
+	wordlist := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	for i, line := range wordlist {
+		wordlist[i] = bytes.TrimSuffix(line, []byte("\r"))
+	}
+
+	f, err := os.Open(*keystoreFile)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+
+	defer f.Close()
+
+	report, err := keystore.New().AuditPasswords(f, wordlist, keystore.AuditOptions{Workers: *workers})
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+
+	if report.StorePassword < 0 {
+		fmt.Fprintln(stdout, "Store password is not in the wordlist")
+	} else {
+		fmt.Fprintf(stdout, "Store password is in the wordlist on line %d\n", report.StorePassword+1)
+	}
+
+	for _, alias := range report.WeakAliases() {
+		fmt.Fprintf(stdout, "Key password of %s is in the wordlist on line %d\n", alias, report.KeyPasswords[alias]+1)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+func build(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	manifestFile := fs.String("manifest", "", "YAML or JSON manifest file")
+	keystoreFile := fs.String("keystore", "", "output keystore file")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *manifestFile == "" || *keystoreFile == "" {
+		return errors.New("build: -manifest and -keystore are required")
+	}
+
+	m, err := keystore.LoadManifest(*manifestFile)
+	if err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.Build(&buf); err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+
+	if err := replaceFile(*keystoreFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("build: write keystore: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+func certReq(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("certreq", flag.ContinueOnError)
+	keystoreFile := fs.String("keystore", "", "keystore file")
+	storePass := newPasswordFlag(fs, "storepass", "keystore password", "Enter keystore password: ")
+	alias := fs.String("alias", "mykey", "alias of the private key entry")
+	keyPass := newPasswordFlag(fs, "keypass", "key password, defaults to the keystore password", "Enter key password: ")
+	sigAlg := fs.String("sigalg", "", "signature algorithm, e.g. SHA256withRSA")
+	dname := fs.String("dname", "", "distinguished name of the subject, defaults to the certificate subject")
+	file := fs.String("file", "", "output file, defaults to the standard output")
+	outform := fs.String("outform", "PEM", "output encoding: PEM or DER")
+
+	var exts stringsFlag
+
+	fs.Var(&exts, "ext", "X.509 extension: SAN, KU or EKU, may be repeated")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *keystoreFile == "" {
+		return errors.New("certreq: -keystore is required")
+	}
+
+	opts, err := certificateRequestOptions(*sigAlg, *dname, *outform, exts)
+	if err != nil {
+		return fmt.Errorf("certreq: %w", err)
+	}
+
+	password, err := storePass.read()
+	if err != nil {
+		return fmt.Errorf("certreq: %w", err)
+	}
+
+	defer zeroing(password)
+
+	keyPassword := password
+	if keyPass.isSet() {
+		if keyPassword, err = keyPass.read(); err != nil {
+			return fmt.Errorf("certreq: %w", err)
+		}
+
+		defer zeroing(keyPassword)
+	}
+
+	ks, err := openKeyStore(*keystoreFile, password)
+	if err != nil {
+		return fmt.Errorf("certreq: %w", err)
+	}
+
+	csr, err := ks.CreateCertificateRequest(*alias, keyPassword, opts)
+	if err != nil {
+		return fmt.Errorf("certreq: %w", err)
+	}
+
+	if *file == "" {
+		_, err = stdout.Write(csr)
+	} else {
+		err = os.WriteFile(*file, csr, 0o644) //nolint:gosec
+	}
+
+	if err != nil {
+		return fmt.Errorf("certreq: write request: %w", err)
+	}
+
+	return nil
+}
+
+func certificateRequestOptions(
+	sigAlg, dname, outform string, extValues []string,
+) (keystore.CertificateRequestOptions, error) {
+	var opts keystore.CertificateRequestOptions
+
+	switch strings.ToUpper(outform) {
+	case "PEM":
+		opts.Encoding = keystore.EncodingPEM
+	case "DER":
+		opts.Encoding = keystore.EncodingDER
+	default:
+		return opts, fmt.Errorf("got unsupported output encoding %q", outform)
+	}
+
+	if sigAlg != "" {
+		algorithm, ok := signatureAlgorithms[strings.ToLower(sigAlg)]
+		if !ok {
+			return opts, fmt.Errorf("got unsupported signature algorithm %q", sigAlg)
+		}
+
+		opts.SignatureAlgorithm = algorithm
+	}
+
+	if dname != "" {
+		subject, err := parseDName(dname)
+		if err != nil {
+			return opts, fmt.Errorf("parse dname: %w", err)
+		}
+
+		opts.Subject = subject
+	}
+
+	exts, err := parseExtensions(extValues)
+	if err != nil {
+		return opts, fmt.Errorf("parse ext: %w", err)
+	}
+
+	if exts.isCA {
+		return opts, errors.New("basic constraints are not supported in certificate requests")
+	}
+
+	opts.DNSNames = exts.dnsNames
+	opts.IPAddresses = exts.ipAddresses
+	opts.EmailAddresses = exts.emailAddresses
+	opts.URIs = exts.uris
+	opts.KeyUsage = exts.keyUsage
+	opts.ExtKeyUsage = exts.extKeyUsage
+
+	return opts, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	oidEmailAddress     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+	oidDomainComponent  = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}
+	oidUserID           = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+	errEmptyDNAttribute = errors.New("empty attribute")
+)
+
+// parseDName parses distinguished name in the keytool -dname format, e.g. "CN=localhost, OU=Dev, O=Example, C=US".
+// Commas and other special characters inside values are escaped with a backslash.
+func parseDName(dname string) (pkix.Name, error) {
+	var name pkix.Name
+
+	rdns, err := splitEscaped(dname, ',')
+	if err != nil {
+		return name, err
+	}
+
+	for _, rdn := range rdns {
+		rdn = strings.TrimSpace(rdn)
+		if rdn == "" {
+			return name, errEmptyDNAttribute
+		}
+
+		key, value, ok := strings.Cut(rdn, "=")
+		if !ok {
+			return name, fmt.Errorf("attribute %q has no value", rdn)
+		}
+
+		value, err = unescape(strings.TrimSpace(value))
+		if err != nil {
+			return name, err
+		}
+
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "CN":
+			name.CommonName = value
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, value)
+		case "O":
+			name.Organization = append(name.Organization, value)
+		case "L":
+			name.Locality = append(name.Locality, value)
+		case "ST", "S":
+			name.Province = append(name.Province, value)
+		case "C":
+			name.Country = append(name.Country, value)
+		case "STREET":
+			name.StreetAddress = append(name.StreetAddress, value)
+		case "POSTALCODE":
+			name.PostalCode = append(name.PostalCode, value)
+		case "SERIALNUMBER":
+			name.SerialNumber = value
+		case "EMAILADDRESS", "EMAIL":
+			name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{Type: oidEmailAddress, Value: value})
+		case "DC":
+			name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{Type: oidDomainComponent, Value: value})
+		case "UID":
+			name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{Type: oidUserID, Value: value})
+		default:
+			return name, fmt.Errorf("got unsupported attribute %q", key)
+		}
+	}
+
+	return name, nil
+}
+
+// splitEscaped splits s by sep ignoring separators escaped with a backslash.
+func splitEscaped(s string, sep byte) ([]string, error) {
+	var (
+		parts []string
+		start int
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i == len(s) {
+				return nil, errors.New("got trailing backslash")
+			}
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+func unescape(s string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				return "", errors.New("got trailing backslash")
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDName(t *testing.T) {
+	table := []struct {
+		dname    string
+		expected string
+	}{
+		{dname: "CN=localhost", expected: "CN=localhost"},
+		{dname: "CN=localhost, OU=Dev, O=Example, L=Kyiv, ST=Kyiv, C=UA", expected: "CN=localhost,OU=Dev,O=Example,L=Kyiv,ST=Kyiv,C=UA"},
+		{dname: `cn=Example\, Inc.,o=Example`, expected: `CN=Example\, Inc.,O=Example`},
+	}
+
+	for _, tt := range table {
+		name, err := parseDName(tt.dname)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, name.String())
+	}
+
+	for _, dname := range []string{"", "CN", "CN=a,,O=b", "XX=a", `CN=a\`} {
+		_, err := parseDName(dname)
+		assert.Errorf(t, err, "dname %q must be rejected", dname)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// stringsFlag collects values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+
+	return nil
+}
+
+// extensions holds X.509 extensions requested with keytool style -ext options.
+type extensions struct {
+	dnsNames       []string
+	ipAddresses    []net.IP
+	emailAddresses []string
+	uris           []*url.URL
+	keyUsage       x509.KeyUsage
+	extKeyUsage    []x509.ExtKeyUsage
+	isCA           bool
+}
+
+var keyUsages = map[string]x509.KeyUsage{
+	"digitalsignature":  x509.KeyUsageDigitalSignature,
+	"nonrepudiation":    x509.KeyUsageContentCommitment,
+	"contentcommitment": x509.KeyUsageContentCommitment,
+	"keyencipherment":   x509.KeyUsageKeyEncipherment,
+	"dataencipherment":  x509.KeyUsageDataEncipherment,
+	"keyagreement":      x509.KeyUsageKeyAgreement,
+	"keycertsign":       x509.KeyUsageCertSign,
+	"crlsign":           x509.KeyUsageCRLSign,
+	"encipheronly":      x509.KeyUsageEncipherOnly,
+	"decipheronly":      x509.KeyUsageDecipherOnly,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"anyextendedkeyusage": x509.ExtKeyUsageAny,
+	"serverauth":          x509.ExtKeyUsageServerAuth,
+	"clientauth":          x509.ExtKeyUsageClientAuth,
+	"codesigning":         x509.ExtKeyUsageCodeSigning,
+	"emailprotection":     x509.ExtKeyUsageEmailProtection,
+	"timestamping":        x509.ExtKeyUsageTimeStamping,
+	"ocspsigning":         x509.ExtKeyUsageOCSPSigning,
+}
+
+// parseExtensions parses values of -ext options, e.g. "SAN=dns:localhost,ip:127.0.0.1",
+// "KU=digitalSignature", "EKU=serverAuth,clientAuth" or "BC=ca:true".
+// The ":critical" or ":c" suffix of the extension name is accepted and ignored.
+func parseExtensions(values []string) (extensions, error) {
+	var exts extensions
+
+	for _, value := range values {
+		name, list, ok := strings.Cut(value, "=")
+		if !ok {
+			return exts, fmt.Errorf("extension %q has no value", value)
+		}
+
+		name, _, _ = strings.Cut(name, ":")
+
+		var err error
+
+		switch strings.ToUpper(name) {
+		case "SAN", "SUBJECTALTERNATIVENAME":
+			err = exts.parseSubjectAltNames(list)
+		case "KU", "KEYUSAGE":
+			err = exts.parseKeyUsage(list)
+		case "EKU", "EXTENDEDKEYUSAGE":
+			err = exts.parseExtKeyUsage(list)
+		case "BC", "BASICCONSTRAINTS":
+			err = exts.parseBasicConstraints(list)
+		default:
+			err = fmt.Errorf("got unsupported extension %q", name)
+		}
+
+		if err != nil {
+			return exts, err
+		}
+	}
+
+	return exts, nil
+}
+
+func (exts *extensions) parseSubjectAltNames(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		kind, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return fmt.Errorf("subject alternative name %q has no type", item)
+		}
+
+		switch strings.ToLower(kind) {
+		case "dns":
+			exts.dnsNames = append(exts.dnsNames, value)
+		case "ip":
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("got invalid ip address %q", value)
+			}
+
+			exts.ipAddresses = append(exts.ipAddresses, ip)
+		case "email":
+			exts.emailAddresses = append(exts.emailAddresses, value)
+		case "uri":
+			u, err := url.Parse(value)
+			if err != nil {
+				return fmt.Errorf("parse uri: %w", err)
+			}
+
+			exts.uris = append(exts.uris, u)
+		default:
+			return fmt.Errorf("got unsupported subject alternative name type %q", kind)
+		}
+	}
+
+	return nil
+}
+
+func (exts *extensions) parseKeyUsage(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		usage, ok := keyUsages[strings.ToLower(item)]
+		if !ok {
+			return fmt.Errorf("got unsupported key usage %q", item)
+		}
+
+		exts.keyUsage |= usage
+	}
+
+	return nil
+}
+
+func (exts *extensions) parseExtKeyUsage(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		usage, ok := extKeyUsages[strings.ToLower(item)]
+		if !ok {
+			return fmt.Errorf("got unsupported extended key usage %q", item)
+		}
+
+		exts.extKeyUsage = append(exts.extKeyUsage, usage)
+	}
+
+	return nil
+}
+
+func (exts *extensions) parseBasicConstraints(list string) error {
+	for _, item := range strings.Split(list, ",") {
+		key, value, _ := strings.Cut(item, ":")
+
+		switch strings.ToLower(key) {
+		case "ca":
+			isCA, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("parse ca: %w", err)
+			}
+
+			exts.isCA = isCA
+		default:
+			return fmt.Errorf("got unsupported basic constraint %q", key)
+		}
+	}
+
+	return nil
+}
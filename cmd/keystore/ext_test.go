@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExtensions(t *testing.T) {
+	exts, err := parseExtensions([]string{
+		"SAN=dns:localhost,ip:127.0.0.1,email:admin@example.com,uri:spiffe://example.com/service",
+		"KU:critical=digitalSignature,keyEncipherment",
+		"EKU=serverAuth,clientAuth",
+		"BC=ca:true",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"localhost"}, exts.dnsNames)
+	assert.True(t, net.IPv4(127, 0, 0, 1).Equal(exts.ipAddresses[0]))
+	assert.Equal(t, []string{"admin@example.com"}, exts.emailAddresses)
+	assert.Equal(t, "spiffe://example.com/service", exts.uris[0].String())
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment, exts.keyUsage)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}, exts.extKeyUsage)
+	assert.True(t, exts.isCA)
+
+	for _, value := range []string{"SAN", "SAN=localhost", "SAN=ip:localhost", "KU=sign", "EKU=any", "BC=ca:maybe", "AIA=x"} {
+		_, err := parseExtensions([]string{value})
+		assert.Errorf(t, err, "extension %q must be rejected", value)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+var signatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"sha256withrsa":   x509.SHA256WithRSA,
+	"sha384withrsa":   x509.SHA384WithRSA,
+	"sha512withrsa":   x509.SHA512WithRSA,
+	"sha256withecdsa": x509.ECDSAWithSHA256,
+	"sha384withecdsa": x509.ECDSAWithSHA384,
+	"sha512withecdsa": x509.ECDSAWithSHA512,
+	"ed25519":         x509.PureEd25519,
+}
+
+func genKeyPair(args []string) error {
+	fs := flag.NewFlagSet("genkeypair", flag.ContinueOnError)
+	keystoreFile := fs.String("keystore", "", "keystore file, created if it does not exist")
+	storePass := newPasswordFlag(fs, "storepass", "keystore password", "Enter keystore password: ")
+	alias := fs.String("alias", "mykey", "alias of the entry")
+	keyPass := newPasswordFlag(fs, "keypass", "key password, defaults to the keystore password", "Enter key password: ")
+	keyAlg := fs.String("keyalg", "RSA", "key algorithm: RSA, EC or Ed25519")
+	keySize := fs.Int("keysize", 0, "key size in bits")
+	sigAlg := fs.String("sigalg", "", "signature algorithm, e.g. SHA256withRSA")
+	dname := fs.String("dname", "", "distinguished name of the subject, e.g. \"CN=localhost, O=Example\"")
+	validity := fs.Int("validity", 90, "validity in days") //nolint:gomnd,mnd
+
+	var exts stringsFlag
+
+	fs.Var(&exts, "ext", "X.509 extension: SAN, KU, EKU or BC, may be repeated")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *keystoreFile == "" || *dname == "" {
+		return errors.New("genkeypair: -keystore and -dname are required")
+	}
+
+	opts, err := keyPairOptions(*keyAlg, *keySize, *sigAlg, *dname, *validity, exts)
+	if err != nil {
+		return fmt.Errorf("genkeypair: %w", err)
+	}
+
+	password, err := storePass.read()
+	if err != nil {
+		return fmt.Errorf("genkeypair: %w", err)
+	}
+
+	defer zeroing(password)
+
+	keyPassword := password
+	if keyPass.isSet() {
+		if keyPassword, err = keyPass.read(); err != nil {
+			return fmt.Errorf("genkeypair: %w", err)
+		}
+
+		defer zeroing(keyPassword)
+	}
+
+	ks, err := readKeyStore(*keystoreFile, password)
+	if err != nil {
+		return fmt.Errorf("genkeypair: %w", err)
+	}
+
+	if hasAlias(ks, *alias) {
+		return fmt.Errorf("genkeypair: alias <%s> already exists", *alias)
+	}
+
+	if err := ks.GenerateKeyPair(*alias, opts, keyPassword); err != nil {
+		return fmt.Errorf("genkeypair: %w", err)
+	}
+
+	if err := writeKeyStore(ks, *keystoreFile, password); err != nil {
+		return fmt.Errorf("genkeypair: %w", err)
+	}
+
+	return nil
+}
+
+func keyPairOptions(
+	keyAlg string, keySize int, sigAlg, dname string, validity int, extValues []string,
+) (keystore.KeyPairOptions, error) {
+	var opts keystore.KeyPairOptions
+
+	switch strings.ToUpper(keyAlg) {
+	case "RSA":
+		opts.Algorithm = keystore.KeyAlgorithmRSA
+	case "EC", "ECDSA":
+		opts.Algorithm = keystore.KeyAlgorithmECDSA
+	case "ED25519", "EDDSA":
+		opts.Algorithm = keystore.KeyAlgorithmEd25519
+	default:
+		return opts, fmt.Errorf("got unsupported key algorithm %q", keyAlg)
+	}
+
+	if sigAlg != "" {
+		algorithm, ok := signatureAlgorithms[strings.ToLower(sigAlg)]
+		if !ok {
+			return opts, fmt.Errorf("got unsupported signature algorithm %q", sigAlg)
+		}
+
+		opts.SignatureAlgorithm = algorithm
+	}
+
+	subject, err := parseDName(dname)
+	if err != nil {
+		return opts, fmt.Errorf("parse dname: %w", err)
+	}
+
+	exts, err := parseExtensions(extValues)
+	if err != nil {
+		return opts, fmt.Errorf("parse ext: %w", err)
+	}
+
+	opts.KeySize = keySize
+	opts.Subject = subject
+	opts.Validity = time.Duration(validity) * 24 * time.Hour
+	opts.DNSNames = exts.dnsNames
+	opts.IPAddresses = exts.ipAddresses
+	opts.EmailAddresses = exts.emailAddresses
+	opts.URIs = exts.uris
+	opts.KeyUsage = exts.keyUsage
+	opts.ExtKeyUsage = exts.extKeyUsage
+	opts.IsCA = exts.isCA
+
+	return opts, nil
+}
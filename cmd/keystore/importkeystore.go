@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+func importKeyStore(args []string) error {
+	fs := flag.NewFlagSet("importkeystore", flag.ContinueOnError)
+	srcFile := fs.String("srckeystore", "", "source keystore file")
+	srcType := fs.String("srcstoretype", "JKS", "source keystore type: JKS, PKCS12, BKS, UBER or BCFKS")
+	srcStorePass := newPasswordFlag(fs, "srcstorepass", "source keystore password", "Enter source keystore password: ")
+	srcKeyPass := newPasswordFlag(fs, "srckeypass", "source key password, tried after the source keystore password",
+		"Enter source key password: ")
+	destFile := fs.String("destkeystore", "", "destination keystore file")
+	destType := fs.String("deststoretype", "PKCS12", "destination keystore type: JKS, PKCS12 or BCFKS")
+	destStorePass := newPasswordFlag(fs, "deststorepass", "destination keystore password",
+		"Enter destination keystore password: ")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *srcFile == "" || *destFile == "" {
+		return errors.New("importkeystore: -srckeystore and -destkeystore are required")
+	}
+
+	srcPassword, err := srcStorePass.read()
+	if err != nil {
+		return fmt.Errorf("importkeystore: %w", err)
+	}
+
+	defer zeroing(srcPassword)
+
+	opts := keystore.UnlockOptions{Candidates: [][]byte{srcPassword}}
+
+	if srcKeyPass.isSet() {
+		keyPassword, err := srcKeyPass.read()
+		if err != nil {
+			return fmt.Errorf("importkeystore: %w", err)
+		}
+
+		defer zeroing(keyPassword)
+
+		opts.Candidates = append(opts.Candidates, keyPassword)
+	}
+
+	destPassword, err := destStorePass.read()
+	if err != nil {
+		return fmt.Errorf("importkeystore: %w", err)
+	}
+
+	defer zeroing(destPassword)
+
+	ks := keystore.New(keystore.WithOrderedAliases())
+
+	content, err := os.ReadFile(*srcFile)
+	if err != nil {
+		return fmt.Errorf("importkeystore: %w", err)
+	}
+
+	if err := loadKeyStore(ks, *srcType, bytes.NewReader(content), srcPassword); err != nil {
+		return fmt.Errorf("importkeystore: load %s: %w", *srcFile, err)
+	}
+
+	var buf bytes.Buffer
+
+	switch strings.ToUpper(*destType) {
+	case "JKS":
+		newPassword := keystore.PasswordFunc(func() ([]byte, error) {
+			return append([]byte{}, destPassword...), nil
+		})
+
+		result, err := ks.ReencryptPrivateKeyEntries(opts, keystore.KeyPasswords(nil, newPassword))
+		if err != nil {
+			return fmt.Errorf("importkeystore: %w", err)
+		}
+
+		if len(result.Locked) > 0 {
+			return fmt.Errorf("importkeystore: decrypt private key %q: %w", result.Locked[0].Alias, result.Locked[0].Err)
+		}
+
+		err = ks.Store(&buf, destPassword)
+		if err != nil {
+			return fmt.Errorf("importkeystore: %w", err)
+		}
+	case "PKCS12":
+		if err := ks.StorePKCS12(&buf, destPassword, opts); err != nil {
+			return fmt.Errorf("importkeystore: %w", err)
+		}
+	case "BCFKS":
+		if err := ks.StoreBCFKS(&buf, destPassword, opts); err != nil {
+			return fmt.Errorf("importkeystore: %w", err)
+		}
+	default:
+		return fmt.Errorf("importkeystore: got unsupported keystore type %q", *destType)
+	}
+
+	if err := replaceFile(*destFile, buf.Bytes()); err != nil {
+		return fmt.Errorf("importkeystore: write keystore: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func list(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	keystoreFile := fs.String("keystore", "", "keystore file")
+	storeType := fs.String("storetype", "JKS", "keystore type: JKS, PKCS12, BKS, UBER or BCFKS")
+	storePass := newPasswordFlag(fs, "storepass", "keystore password", "Enter keystore password: ")
+	format := fs.String("format", "text", "output format: text, json or yaml")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *keystoreFile == "" {
+		return errors.New("list: -keystore is required")
+	}
+
+	password, err := storePass.read()
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	defer zeroing(password)
+
+	ks, err := openKeyStoreOfType(*keystoreFile, *storeType, password)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	switch strings.ToLower(*format) {
+	case "json":
+		err = ks.WriteJSON(stdout)
+	case "yaml":
+		err = ks.WriteYAML(stdout)
+	case "text":
+		infos, listErr := ks.List()
+		if listErr != nil {
+			return fmt.Errorf("list: %w", listErr)
+		}
+
+		fmt.Fprintf(stdout, "Your keystore contains %d entries\n", len(infos))
+
+		for _, info := range infos {
+			fmt.Fprintf(stdout, "\n%s, %s, %s,\n", info.Alias, info.CreationTime.Format("Jan 2, 2006"), info.EntryType)
+
+			if len(info.Certificates) > 0 {
+				fmt.Fprintf(stdout, "Certificate fingerprint (SHA-256): %s\n", info.Certificates[0].SHA256Fingerprint)
+			}
+		}
+	default:
+		return fmt.Errorf("list: got unsupported format %q", *format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+// Command keystore is a minimal keytool replacement built on top of the keystore package.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: keystore <command> [options]
+
+commands:
+  genkeypair  generate a key pair and a self-signed certificate
+  certreq     generate a certificate signing request for a private key entry
+  list        list entries of the keystore as text, JSON or YAML
+  build       build a keystore from a YAML or JSON manifest
+  importkeystore
+              convert a keystore between JKS, PKCS12 and BCFKS
+  audit       find store and key passwords of a JKS keystore in a wordlist
+
+run "keystore <command> -h" to see options of the command`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "keystore:", err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "genkeypair":
+		return genKeyPair(args[1:])
+	case "certreq":
+		return certReq(args[1:], stdout)
+	case "list":
+		return list(args[1:], stdout)
+	case "build":
+		return build(args[1:])
+	case "importkeystore":
+		return importKeyStore(args[1:])
+	case "audit":
+		return audit(args[1:], stdout)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprintln(stdout, usage)
+
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
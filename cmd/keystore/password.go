@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+// passwordFlag is a keytool-like password option: -name value, -name:env VAR or -name:file FILE.
+type passwordFlag struct {
+	value, env, file string
+	prompt           string
+}
+
+func newPasswordFlag(fs *flag.FlagSet, name, usage, prompt string) *passwordFlag {
+	p := &passwordFlag{prompt: prompt}
+
+	fs.StringVar(&p.value, name, "", usage)
+	fs.StringVar(&p.env, name+":env", "", usage+" from the environment variable")
+	fs.StringVar(&p.file, name+":file", "", usage+" from the file")
+
+	return p
+}
+
+func (p *passwordFlag) isSet() bool {
+	return p.value != "" || p.env != "" || p.file != ""
+}
+
+// source returns the password source, prompting on the terminal if no option is set.
+func (p *passwordFlag) source() keystore.PasswordSource {
+	switch {
+	case p.value != "":
+		return keystore.PasswordFunc(func() ([]byte, error) {
+			return []byte(p.value), nil
+		})
+	case p.env != "":
+		return keystore.EnvPassword(p.env)
+	case p.file != "":
+		return keystore.FilePassword(p.file)
+	default:
+		return keystore.PromptPassword(p.prompt)
+	}
+}
+
+// read returns the password once so the user is prompted at most one time.
+func (p *passwordFlag) read() ([]byte, error) {
+	return p.source().Password()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+// readKeyStore loads JKS keystore from filename or returns an empty keystore if the file does not exist.
+func readKeyStore(filename string, password []byte) (keystore.KeyStore, error) {
+	return readKeyStoreOfType(filename, "JKS", password)
+}
+
+// readKeyStoreOfType loads keystore of storeType from filename or returns an empty keystore
+// if the file does not exist.
+func readKeyStoreOfType(filename, storeType string, password []byte) (keystore.KeyStore, error) {
+	ks, err := openKeyStoreOfType(filename, storeType, password)
+	if errors.Is(err, os.ErrNotExist) {
+		return keystore.New(keystore.WithOrderedAliases()), nil
+	}
+
+	return ks, err
+}
+
+// openKeyStore loads JKS keystore from filename failing if the file does not exist.
+func openKeyStore(filename string, password []byte) (keystore.KeyStore, error) {
+	return openKeyStoreOfType(filename, "JKS", password)
+}
+
+// openKeyStoreOfType loads keystore of storeType from filename failing if the file does not exist.
+func openKeyStoreOfType(filename, storeType string, password []byte) (keystore.KeyStore, error) {
+	ks := keystore.New(keystore.WithOrderedAliases())
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return ks, err
+	}
+
+	defer f.Close()
+
+	if err := loadKeyStore(ks, storeType, f, password); err != nil {
+		return ks, fmt.Errorf("load %s: %w", filename, err)
+	}
+
+	return ks, nil
+}
+
+// loadKeyStore reads JKS, PKCS12, BKS, UBER or BCFKS keystore. Private keys of other types than JKS are
+// protected with the store password.
+func loadKeyStore(ks keystore.KeyStore, storeType string, r io.Reader, password []byte) error {
+	switch strings.ToUpper(storeType) {
+	case "JKS":
+		return ks.Load(r, password)
+	case "PKCS12":
+		return ks.LoadPKCS12(r, password, nil)
+	case "BKS":
+		return ks.LoadBKS(r, password, nil)
+	case "UBER":
+		return ks.LoadUBER(r, password, nil)
+	case "BCFKS":
+		return ks.LoadBCFKS(r, password, nil)
+	default:
+		return fmt.Errorf("got unsupported keystore type %q", storeType)
+	}
+}
+
+// writeKeyStore atomically replaces filename with the keystore content.
+func writeKeyStore(ks keystore.KeyStore, filename string, password []byte) error {
+	var buf bytes.Buffer
+
+	if err := ks.Store(&buf, password); err != nil {
+		return fmt.Errorf("store keystore: %w", err)
+	}
+
+	return replaceFile(filename, buf.Bytes())
+}
+
+// replaceFile atomically replaces filename with data, so an interrupted write does not leave it truncated.
+func replaceFile(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filename)
+}
+
+func hasAlias(ks keystore.KeyStore, alias string) bool {
+	return ks.IsPrivateKeyEntry(alias) || ks.IsTrustedCertificateEntry(alias) ||
+		ks.IsSecretKeyEntry(alias) || ks.IsSealedEntry(alias)
+}
+
+func zeroing(buf []byte) {
+	for i := range buf {
+		buf[i] = 0
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyCommandsMissingKeyStore(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.jks")
+
+	ks, err := readKeyStoreOfType(missing, "JKS", []byte("password"))
+	require.NoError(t, err)
+	assert.Empty(t, ks.Aliases())
+
+	var stdout bytes.Buffer
+
+	err = run([]string{"list", "-keystore", missing, "-storepass", "password"}, &stdout)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	assert.Zero(t, stdout.Len())
+
+	err = run([]string{"certreq", "-keystore", missing, "-storepass", "password", "-alias", "key"}, &stdout)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	assert.Zero(t, stdout.Len())
+}
+
+func TestBuildReplacesKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	keystoreFile := filepath.Join(dir, "keystore.jks")
+	manifestFile := filepath.Join(dir, "manifest.yaml")
+
+	cert, err := filepath.Abs("../../testdata/cert.pem")
+	require.NoError(t, err)
+
+	t.Setenv("STORE_PASSWORD", "password")
+	require.NoError(t, os.WriteFile(manifestFile, []byte(`storePassword:
+  env: STORE_PASSWORD
+entries:
+  - alias: ca
+    type: TrustedCertificateEntry
+    certificates: [`+cert+`]
+`), 0o600))
+	require.NoError(t, os.WriteFile(keystoreFile, []byte("old"), 0o600))
+
+	require.NoError(t, run([]string{"build", "-manifest", manifestFile, "-keystore", keystoreFile}, &bytes.Buffer{}))
+
+	ks, err := openKeyStore(keystoreFile, []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ca"}, ks.Aliases())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
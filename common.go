@@ -2,6 +2,9 @@ package keystore
 
 import (
 	"encoding/binary"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 const (
@@ -18,10 +21,31 @@ var byteOrder = binary.BigEndian
 
 var whitenerMessage = []byte("Mighty Aphrodite")
 
-func passwordBytes(password []byte) []byte {
+// passwordBytes encodes UTF-8 password as UTF-16BE the same way Java encodes char[] password.
+// Invalid UTF-8 bytes are encoded as U+FFFD. Every byte is encoded as a character if legacy is set.
+func passwordBytes(password []byte, legacy bool) []byte {
+	// every UTF-8 byte takes at most two bytes in UTF-16, so result is never reallocated leaving a copy
 	result := make([]byte, 0, len(password)*2) //nolint:gomnd,mnd
-	for _, b := range password {
-		result = append(result, 0, b)
+
+	if legacy {
+		for _, b := range password {
+			result = append(result, 0, b)
+		}
+
+		return result
+	}
+
+	for rest := password; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+
+		if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+			result = append(result, byte(r1>>8), byte(r1), byte(r2>>8), byte(r2)) //nolint:gomnd,mnd
+
+			continue
+		}
+
+		result = append(result, byte(r>>8), byte(r)) //nolint:gomnd,mnd
 	}
 
 	return result
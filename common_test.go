@@ -3,6 +3,7 @@ package keystore
 import (
 	"crypto/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,7 +57,52 @@ func TestPasswordBytes(t *testing.T) {
 	}
 
 	for _, tt := range table {
-		output := passwordBytes(tt.input)
+		output := passwordBytes(tt.input, true)
 		assert.Equal(t, tt.output, output, "convert password bytes")
 	}
 }
+
+func TestPasswordBytesUTF16(t *testing.T) {
+	table := []struct {
+		input  string
+		output []byte
+	}{
+		{input: "", output: []byte{}},
+		{input: "password", output: []byte{0, 'p', 0, 'a', 0, 's', 0, 's', 0, 'w', 0, 'o', 0, 'r', 0, 'd'}},
+		{input: "é", output: []byte{0x00, 0xe9}},
+		{input: "ü1", output: []byte{0x00, 0xfc, 0x00, '1'}},
+		{input: "密码", output: []byte{0x5b, 0xc6, 0x78, 0x01}},
+		{input: "😀", output: []byte{0xd8, 0x3d, 0xde, 0x00}},
+		{input: "\xe9", output: []byte{0xff, 0xfd}},
+	}
+
+	for _, tt := range table {
+		assert.Equal(t, tt.output, passwordBytes([]byte(tt.input), false), tt.input)
+	}
+}
+
+// newTestKeyStore returns keystore with ordered aliases holding PrivateKeyEntry made of key.pem and cert.pem
+// by every alias of keyPasswords encrypted with its password and TrustedCertificateEntry with cert.pem
+// by certAlias.
+func newTestKeyStore(t *testing.T, keyPasswords map[string]string, certAlias string, options ...Option) KeyStore {
+	t.Helper()
+
+	ks := New(append([]Option{WithOrderedAliases()}, options...)...)
+	creationTime := time.Date(2020, 10, 29, 19, 25, 12, 0, time.UTC)
+	cert := Certificate{Type: X509CertificateType, Content: readCertificate(t)}
+
+	for alias, password := range keyPasswords {
+		require.NoError(t, ks.SetPrivateKeyEntry(alias, PrivateKeyEntry{
+			CreationTime:     creationTime,
+			PrivateKey:       readPrivateKey(t),
+			CertificateChain: []Certificate{cert},
+		}, []byte(password)))
+	}
+
+	require.NoError(t, ks.SetTrustedCertificateEntry(certAlias, TrustedCertificateEntry{
+		CreationTime: creationTime,
+		Certificate:  cert,
+	}))
+
+	return ks
+}
@@ -66,7 +66,7 @@ func (d decoder) readCertificate(version uint32) (Certificate, error) {
 
 	switch version {
 	case version01:
-		certType = defaultCertificateType
+		certType = X509CertificateType
 	case version02:
 		readCertType, err := d.readString()
 		if err != nil {
@@ -388,7 +388,7 @@ func TestReadCertificate(t *testing.T) {
 				input:   input,
 				version: version01,
 				cert: Certificate{
-					Type:    defaultCertificateType,
+					Type:    X509CertificateType,
 					Content: []byte{},
 				},
 				err:  nil,
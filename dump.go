@@ -0,0 +1,262 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	privateKeyEntryType         = "PrivateKeyEntry"
+	trustedCertificateEntryType = "TrustedCertificateEntry"
+	secretKeyEntryType          = "SecretKeyEntry"
+	sealedEntryType             = "SealedEntry"
+)
+
+// EntryInfo is a machine-readable description of the keystore entry. It never holds private keys.
+type EntryInfo struct {
+	Alias        string            `json:"alias"                  yaml:"alias"`
+	EntryType    string            `json:"entryType"              yaml:"entryType"`
+	CreationTime time.Time         `json:"creationTime"           yaml:"creationTime"`
+	ChainLength  int               `json:"chainLength"            yaml:"chainLength"`
+	Certificates []CertificateInfo `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"   yaml:"attributes,omitempty"`
+}
+
+// CertificateInfo is a machine-readable description of the certificate.
+// Only type and fingerprints are filled for certificates of types other than X.509.
+type CertificateInfo struct {
+	Type               string     `json:"type"                         yaml:"type"`
+	Subject            string     `json:"subject,omitempty"            yaml:"subject,omitempty"`
+	Issuer             string     `json:"issuer,omitempty"             yaml:"issuer,omitempty"`
+	SerialNumber       string     `json:"serialNumber,omitempty"       yaml:"serialNumber,omitempty"`
+	NotBefore          *time.Time `json:"notBefore,omitempty"          yaml:"notBefore,omitempty"`
+	NotAfter           *time.Time `json:"notAfter,omitempty"           yaml:"notAfter,omitempty"`
+	SignatureAlgorithm string     `json:"signatureAlgorithm,omitempty" yaml:"signatureAlgorithm,omitempty"`
+	PublicKeyAlgorithm string     `json:"publicKeyAlgorithm,omitempty" yaml:"publicKeyAlgorithm,omitempty"`
+	PublicKeySize      int        `json:"publicKeySize,omitempty"      yaml:"publicKeySize,omitempty"`
+	IsCA               bool       `json:"isCA,omitempty"               yaml:"isCA,omitempty"`
+	KeyUsage           []string   `json:"keyUsage,omitempty"           yaml:"keyUsage,omitempty"`
+	ExtKeyUsage        []string   `json:"extKeyUsage,omitempty"        yaml:"extKeyUsage,omitempty"`
+	DNSNames           []string   `json:"dnsNames,omitempty"           yaml:"dnsNames,omitempty"`
+	IPAddresses        []string   `json:"ipAddresses,omitempty"        yaml:"ipAddresses,omitempty"`
+	EmailAddresses     []string   `json:"emailAddresses,omitempty"     yaml:"emailAddresses,omitempty"`
+	URIs               []string   `json:"uris,omitempty"               yaml:"uris,omitempty"`
+	SHA1Fingerprint    string     `json:"sha1Fingerprint"              yaml:"sha1Fingerprint"`
+	SHA256Fingerprint  string     `json:"sha256Fingerprint"            yaml:"sha256Fingerprint"`
+}
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digitalSignature"},
+	{x509.KeyUsageContentCommitment, "nonRepudiation"},
+	{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "keyAgreement"},
+	{x509.KeyUsageCertSign, "keyCertSign"},
+	{x509.KeyUsageCRLSign, "cRLSign"},
+	{x509.KeyUsageEncipherOnly, "encipherOnly"},
+	{x509.KeyUsageDecipherOnly, "decipherOnly"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "anyExtendedKeyUsage",
+	x509.ExtKeyUsageServerAuth:      "serverAuth",
+	x509.ExtKeyUsageClientAuth:      "clientAuth",
+	x509.ExtKeyUsageCodeSigning:     "codeSigning",
+	x509.ExtKeyUsageEmailProtection: "emailProtection",
+	x509.ExtKeyUsageTimeStamping:    "timeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+// List returns description of every entry of the keystore in the order of Aliases.
+func (ks KeyStore) List() ([]EntryInfo, error) {
+	aliases := ks.Aliases()
+	infos := make([]EntryInfo, 0, len(aliases))
+
+	for _, alias := range aliases {
+		var (
+			info  EntryInfo
+			certs []Certificate
+		)
+
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			info = EntryInfo{
+				EntryType:    privateKeyEntryType,
+				CreationTime: typedEntry.CreationTime,
+				Attributes:   typedEntry.Attributes,
+			}
+			certs = typedEntry.CertificateChain
+		case TrustedCertificateEntry:
+			info = EntryInfo{
+				EntryType:    trustedCertificateEntryType,
+				CreationTime: typedEntry.CreationTime,
+				Attributes:   typedEntry.Attributes,
+			}
+			certs = []Certificate{typedEntry.Certificate}
+		case SecretKeyEntry:
+			info = EntryInfo{
+				EntryType:    secretKeyEntryType,
+				CreationTime: typedEntry.CreationTime,
+				Attributes:   typedEntry.Attributes,
+			}
+			certs = typedEntry.CertificateChain
+		case SealedEntry:
+			info = EntryInfo{EntryType: sealedEntryType, CreationTime: typedEntry.CreationTime}
+			certs = typedEntry.CertificateChain
+		default:
+			return nil, fmt.Errorf("got invalid entry %q", alias)
+		}
+
+		info.Alias = alias
+		info.ChainLength = len(certs)
+
+		for i, cert := range certs {
+			certInfo, err := describeCertificate(cert)
+			if err != nil {
+				return nil, fmt.Errorf("describe %d certificate of %q: %w", i, alias, err)
+			}
+
+			info.Certificates = append(info.Certificates, certInfo)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// WriteJSON writes description of every entry of the keystore into w as JSON array.
+func (ks KeyStore) WriteJSON(w io.Writer) error {
+	infos, err := ks.List()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(infos); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+
+	return nil
+}
+
+// WriteYAML writes description of every entry of the keystore into w as YAML sequence.
+func (ks KeyStore) WriteYAML(w io.Writer) error {
+	infos, err := ks.List()
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2) //nolint:gomnd,mnd
+
+	if err := enc.Encode(infos); err != nil {
+		return fmt.Errorf("encode yaml: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encode yaml: %w", err)
+	}
+
+	return nil
+}
+
+func describeCertificate(c Certificate) (CertificateInfo, error) {
+	sha1Digest := sha1.Sum(c.Content)
+	sha256Digest := sha256.Sum256(c.Content)
+
+	info := CertificateInfo{
+		Type:              c.Type,
+		SHA1Fingerprint:   fingerprint(sha1Digest[:]),
+		SHA256Fingerprint: fingerprint(sha256Digest[:]),
+	}
+
+	if !isX509CertificateType(c.Type) {
+		return info, nil
+	}
+
+	cert, err := x509.ParseCertificate(c.Content)
+	if err != nil {
+		return info, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	info.Subject = cert.Subject.String()
+	info.Issuer = cert.Issuer.String()
+	info.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+	info.NotBefore = &cert.NotBefore
+	info.NotAfter = &cert.NotAfter
+	info.SignatureAlgorithm = cert.SignatureAlgorithm.String()
+	info.PublicKeyAlgorithm = cert.PublicKeyAlgorithm.String()
+	info.PublicKeySize = publicKeySize(cert.PublicKey)
+	info.IsCA = cert.IsCA
+	info.DNSNames = cert.DNSNames
+	info.EmailAddresses = cert.EmailAddresses
+
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+
+	for _, ku := range keyUsageNames {
+		if cert.KeyUsage&ku.usage != 0 {
+			info.KeyUsage = append(info.KeyUsage, ku.name)
+		}
+	}
+
+	for _, eku := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[eku]
+		if !ok {
+			name = fmt.Sprintf("%d", eku)
+		}
+
+		info.ExtKeyUsage = append(info.ExtKeyUsage, name)
+	}
+
+	for _, oid := range cert.UnknownExtKeyUsage {
+		info.ExtKeyUsage = append(info.ExtKeyUsage, oid.String())
+	}
+
+	return info, nil
+}
+
+func publicKeySize(key any) int {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return ed25519.PublicKeySize * 8 //nolint:gomnd,mnd
+	default:
+		return 0
+	}
+}
+
+// fingerprint formats digest as colon separated upper case hex bytes like keytool does.
+func fingerprint(digest []byte) string {
+	parts := make([]string, len(digest))
+	for i, b := range digest {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
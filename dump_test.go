@@ -0,0 +1,88 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestList(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"pke": "password"}, "tce")
+	require.NoError(t, ks.SetTrustedCertificateEntry("other", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "other", Content: []byte{1, 2, 3}},
+	}))
+
+	infos, err := ks.List()
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+
+	assert.Equal(t, "other", infos[0].Alias)
+	assert.Equal(t, trustedCertificateEntryType, infos[0].EntryType)
+	assert.Equal(t, []CertificateInfo{{
+		Type:              "other",
+		SHA1Fingerprint:   "70:37:80:71:98:C2:2A:7D:2B:08:07:37:1D:76:37:79:A8:4F:DF:CF",
+		SHA256Fingerprint: "03:90:58:C6:F2:C0:CB:49:2C:53:3B:0A:4D:14:EF:77:CC:0F:78:AB:CC:CE:D5:28:7D:84:A1:A2:01:1C:FB:81",
+	}}, infos[0].Certificates)
+
+	assert.Equal(t, "pke", infos[1].Alias)
+	assert.Equal(t, privateKeyEntryType, infos[1].EntryType)
+	assert.Equal(t, 1, infos[1].ChainLength)
+
+	assert.Equal(t, "tce", infos[2].Alias)
+	assert.Equal(t, trustedCertificateEntryType, infos[2].EntryType)
+	assert.Equal(t, 1, infos[2].ChainLength)
+
+	notBefore := time.Date(2020, 10, 29, 19, 25, 12, 0, time.UTC)
+	notAfter := time.Date(2021, 10, 29, 19, 25, 12, 0, time.UTC)
+
+	cert := infos[2].Certificates[0]
+	assert.Equal(t, X509CertificateType, cert.Type)
+	assert.Equal(t, "CN=localhost", cert.Subject)
+	assert.Equal(t, "CN=localhost", cert.Issuer)
+	assert.Equal(t, "82bf2e6e34ba95c5", cert.SerialNumber)
+	assert.True(t, notBefore.Equal(*cert.NotBefore))
+	assert.True(t, notAfter.Equal(*cert.NotAfter))
+	assert.Equal(t, "SHA256-RSA", cert.SignatureAlgorithm)
+	assert.Equal(t, "RSA", cert.PublicKeyAlgorithm)
+	assert.Equal(t, 2048, cert.PublicKeySize)
+	assert.Equal(t, "6D:21:B0:1A:68:63:47:3A:5B:36:7D:05:BC:BC:64:43:07:C5:53:21", cert.SHA1Fingerprint)
+	assert.Equal(t,
+		"A7:61:1D:65:4D:29:E7:91:84:EE:83:A7:B9:FC:4C:AA:4D:5B:2B:59:A1:23:C4:B8:69:D4:B3:21:2B:DB:C9:A3",
+		cert.SHA256Fingerprint)
+}
+
+func TestWriteJSONAndYAML(t *testing.T) {
+	ks := newTestKeyStore(t, map[string]string{"pke": "password"}, "tce")
+
+	expected, err := ks.List()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ks.WriteJSON(&buf))
+	assert.NotContains(t, buf.String(), "privateKey")
+
+	var fromJSON []EntryInfo
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fromJSON))
+	assert.Len(t, fromJSON, len(expected))
+	assert.Equal(t, expected[1].Certificates[0].SHA256Fingerprint, fromJSON[1].Certificates[0].SHA256Fingerprint)
+	assert.True(t, expected[0].CreationTime.Equal(fromJSON[0].CreationTime))
+
+	buf.Reset()
+
+	require.NoError(t, ks.WriteYAML(&buf))
+
+	var fromYAML []EntryInfo
+
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &fromYAML))
+	assert.Len(t, fromYAML, len(expected))
+	assert.Equal(t, expected[1].Certificates[0].Subject, fromYAML[1].Certificates[0].Subject)
+	assert.Equal(t, expected[0].EntryType, fromYAML[0].EntryType)
+}
@@ -8,8 +8,9 @@ import (
 )
 
 type encoder struct {
-	w io.Writer
-	h hash.Hash
+	w       io.Writer
+	h       hash.Hash
+	version uint32
 }
 
 func (e encoder) writeUint16(value uint16) error {
@@ -66,8 +67,11 @@ func (e encoder) writeString(value string) error {
 }
 
 func (e encoder) writeCertificate(cert Certificate) error {
-	if err := e.writeString(cert.Type); err != nil {
-		return fmt.Errorf("write type: %w", err)
+	// version 1 has no certificate type, every certificate is X.509
+	if e.version != version01 {
+		if err := e.writeString(cert.Type); err != nil {
+			return fmt.Errorf("write type: %w", err)
+		}
 	}
 
 	certLen := uint64(len(cert.Content))
@@ -0,0 +1,203 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	oidExtensionSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+)
+
+// IssueOptions describes certificate issued with a certificate authority key held in the keystore.
+type IssueOptions struct {
+	// SerialNumber is generated randomly if nil.
+	SerialNumber *big.Int
+	// NotBefore defaults to the current time.
+	NotBefore time.Time
+	// Validity defaults to 90 days.
+	Validity time.Duration
+
+	// KeyUsage and ExtKeyUsage override usages requested in certificate signing request if set.
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// IsCA issues certificate authority certificate. Key usage requested in certificate signing request
+	// is dropped even if it is honored.
+	IsCA bool
+
+	// HonoredExtensions are ids of extensions requested in certificate signing request which are copied
+	// to the issued certificate, the other requested extensions are dropped like keytool does without -ext honored.
+	HonoredExtensions []asn1.ObjectIdentifier
+
+	// SignatureAlgorithm is chosen based on the issuer key algorithm if unknown.
+	SignatureAlgorithm x509.SignatureAlgorithm
+}
+
+// SignCertificateRequest issues certificate for PEM or DER encoded PKCS#10 certificate signing request
+// using PrivateKeyEntry from the keystore by the issuer alias decrypted with the password as the issuer.
+// Subject and subject alternative names are copied from the request, other requested extensions are copied
+// only if they are listed in HonoredExtensions of opts. Returned chain starts with the issued certificate
+// followed by the issuer chain.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) SignCertificateRequest(
+	issuerAlias string, password []byte, csr []byte, opts IssueOptions,
+) ([]Certificate, error) {
+	if b, _ := pem.Decode(csr); b != nil {
+		csr = b.Bytes
+	}
+
+	req, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate request: %w", err)
+	}
+
+	if err := req.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("check certificate request signature: %w", err)
+	}
+
+	template := &x509.Certificate{
+		RawSubject:     req.RawSubject,
+		DNSNames:       req.DNSNames,
+		IPAddresses:    req.IPAddresses,
+		EmailAddresses: req.EmailAddresses,
+		URIs:           req.URIs,
+	}
+
+	for _, ext := range req.Extensions {
+		switch {
+		case !opts.honors(ext.Id):
+			continue
+		case ext.Id.Equal(oidExtensionSubjectAltName), ext.Id.Equal(oidExtensionBasicConstraints):
+			continue
+		case ext.Id.Equal(oidExtensionKeyUsage) && (opts.KeyUsage != 0 || opts.IsCA):
+			continue
+		case ext.Id.Equal(oidExtensionExtendedKeyUsage) && len(opts.ExtKeyUsage) > 0:
+			continue
+		}
+
+		template.ExtraExtensions = append(template.ExtraExtensions, ext)
+	}
+
+	return ks.issueCertificate(issuerAlias, password, template, req.PublicKey, opts)
+}
+
+// SignCertificate issues certificate described by template for the public key using PrivateKeyEntry from
+// the keystore by the issuer alias decrypted with the password as the issuer. Serial number and validity
+// period of the template are filled from opts if empty. Returned chain starts with the issued certificate
+// followed by the issuer chain.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) SignCertificate(
+	issuerAlias string, password []byte, template *x509.Certificate, publicKey crypto.PublicKey, opts IssueOptions,
+) ([]Certificate, error) {
+	t := *template
+
+	return ks.issueCertificate(issuerAlias, password, &t, publicKey, opts)
+}
+
+func (ks KeyStore) issueCertificate(
+	issuerAlias string, password []byte, template *x509.Certificate, publicKey crypto.PublicKey, opts IssueOptions,
+) ([]Certificate, error) {
+	issuer, err := ks.GetPrivateKeyEntry(issuerAlias, password)
+	if err != nil {
+		return nil, fmt.Errorf("get issuer entry: %w", err)
+	}
+
+	defer issuer.Destroy()
+
+	if len(issuer.CertificateChain) == 0 {
+		return nil, errors.New("got issuer entry without certificate chain")
+	}
+
+	issuerCert, err := x509.ParseCertificate(issuer.CertificateChain[0].Content)
+	if err != nil {
+		return nil, fmt.Errorf("parse issuer certificate: %w", err)
+	}
+
+	issuerKey, err := parsePrivateKey(issuer.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := opts.apply(ks, template); err != nil {
+		return nil, err
+	}
+
+	der, err := x509.CreateCertificate(ks.r, template, issuerCert, publicKey, issuerKey)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	chain := make([]Certificate, 0, len(issuer.CertificateChain)+1)
+	chain = append(chain, Certificate{Type: X509CertificateType, Content: der})
+	chain = append(chain, issuer.CertificateChain...)
+
+	return chain, nil
+}
+
+func (opts IssueOptions) honors(id asn1.ObjectIdentifier) bool {
+	for _, honored := range opts.HonoredExtensions {
+		if honored.Equal(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (opts IssueOptions) apply(ks KeyStore, template *x509.Certificate) error {
+	if opts.SerialNumber != nil {
+		template.SerialNumber = opts.SerialNumber
+	}
+
+	if template.SerialNumber == nil {
+		serialNumber, err := randomSerialNumber(ks.r)
+		if err != nil {
+			return err
+		}
+
+		template.SerialNumber = serialNumber
+	}
+
+	if !opts.NotBefore.IsZero() {
+		template.NotBefore = opts.NotBefore
+	}
+
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now()
+	}
+
+	if opts.Validity != 0 {
+		template.NotAfter = template.NotBefore.Add(opts.Validity)
+	}
+
+	if template.NotAfter.IsZero() {
+		template.NotAfter = template.NotBefore.Add(defaultValidity)
+	}
+
+	if opts.KeyUsage != 0 {
+		template.KeyUsage = opts.KeyUsage
+	}
+
+	if len(opts.ExtKeyUsage) > 0 {
+		template.ExtKeyUsage = opts.ExtKeyUsage
+	}
+
+	if opts.IsCA {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	if opts.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		template.SignatureAlgorithm = opts.SignatureAlgorithm
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignCertificateRequest(t *testing.T) {
+	password := []byte("password")
+
+	ca := New()
+
+	err := ca.GenerateKeyPair("ca", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "ca"},
+		IsCA:      true,
+	}, password)
+	require.NoError(t, err)
+
+	ks := New()
+
+	err = ks.GenerateKeyPair("alias", KeyPairOptions{
+		Algorithm: KeyAlgorithmRSA,
+		KeySize:   1024,
+		Subject:   pkix.Name{CommonName: "localhost"},
+	}, password)
+	require.NoError(t, err)
+
+	csr, err := ks.CreateCertificateRequest("alias", password, CertificateRequestOptions{
+		DNSNames:    []string{"localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	require.NoError(t, err)
+
+	chain, err := ca.SignCertificateRequest("ca", password, csr, IssueOptions{
+		Validity:          time.Hour,
+		KeyUsage:          x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		HonoredExtensions: []asn1.ObjectIdentifier{oidExtensionExtendedKeyUsage},
+	})
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+
+	caChain, err := ca.GetPrivateKeyEntryCertificateChain("ca")
+	require.NoError(t, err)
+	assert.Equal(t, caChain[0], chain[1])
+
+	leaf, err := x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+
+	root, err := x509.ParseCertificate(chain[1].Content)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		DNSName:   "localhost",
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "CN=localhost", leaf.Subject.String())
+	assert.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment, leaf.KeyUsage)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, leaf.ExtKeyUsage)
+	assert.Equal(t, time.Hour, leaf.NotAfter.Sub(leaf.NotBefore))
+	assert.False(t, leaf.IsCA)
+
+	var reply []byte
+	for _, cert := range chain {
+		reply = append(reply, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Content})...)
+	}
+
+	err = ks.InstallCertificateReply("alias", reply, nil)
+	require.NoError(t, err)
+
+	installed, err := ks.GetPrivateKeyEntryCertificateChain("alias")
+	require.NoError(t, err)
+	assert.Equal(t, chain, installed)
+}
+
+func TestSignCertificate(t *testing.T) {
+	password := []byte("password")
+
+	ca := New()
+
+	err := ca.GenerateKeyPair("ca", KeyPairOptions{
+		Algorithm: KeyAlgorithmEd25519,
+		Subject:   pkix.Name{CommonName: "ca"},
+		IsCA:      true,
+	}, password)
+	require.NoError(t, err)
+
+	key := generateTestKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "intermediate"},
+	}
+
+	chain, err := ca.SignCertificate("ca", password, template, key.Public(), IssueOptions{IsCA: true})
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+
+	cert, err := x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewInt(42), cert.SerialNumber)
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, defaultValidity, cert.NotAfter.Sub(cert.NotBefore))
+	assert.Equal(t, key.Public(), cert.PublicKey)
+	assert.True(t, template.NotBefore.IsZero(), "template must not be modified")
+
+	_, err = ca.SignCertificate("ca", []byte("wrong"), template, key.Public(), IssueOptions{})
+	require.Error(t, err)
+
+	err = ca.SetPrivateKeyEntry("nochain", PrivateKeyEntry{PrivateKey: readPrivateKey(t)}, password)
+	require.NoError(t, err)
+
+	_, err = ca.SignCertificate("nochain", password, template, key.Public(), IssueOptions{})
+	require.Error(t, err)
+}
+
+func TestSignCertificateRequestExtensions(t *testing.T) {
+	password := []byte("password")
+
+	ca := New()
+
+	err := ca.GenerateKeyPair("ca", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "ca"},
+		IsCA:      true,
+	}, password)
+	require.NoError(t, err)
+
+	ks := New()
+
+	err = ks.GenerateKeyPair("alias", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "intermediate"},
+	}, password)
+	require.NoError(t, err)
+
+	oidCustom := asn1.ObjectIdentifier{1, 2, 3, 4}
+
+	csr, err := ks.CreateCertificateRequest("alias", password, CertificateRequestOptions{
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtraExtensions: []pkix.Extension{{Id: oidCustom, Critical: true, Value: []byte{5, 0}}},
+	})
+	require.NoError(t, err)
+
+	hasExtension := func(cert *x509.Certificate, id asn1.ObjectIdentifier) bool {
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(id) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	chain, err := ca.SignCertificateRequest("ca", password, csr, IssueOptions{})
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+	assert.False(t, hasExtension(cert, oidCustom))
+	assert.False(t, hasExtension(cert, oidExtensionKeyUsage))
+	assert.False(t, hasExtension(cert, oidExtensionExtendedKeyUsage))
+
+	chain, err = ca.SignCertificateRequest("ca", password, csr, IssueOptions{
+		IsCA:              true,
+		HonoredExtensions: []asn1.ObjectIdentifier{oidCustom, oidExtensionKeyUsage},
+	})
+	require.NoError(t, err)
+
+	cert, err = x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+	assert.True(t, hasExtension(cert, oidCustom))
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, x509.KeyUsageCertSign, cert.KeyUsage)
+}
@@ -0,0 +1,209 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultRSAKeySize   = 2048
+	defaultECDSAKeySize = 256
+	defaultValidity     = 90 * 24 * time.Hour
+	serialNumberBits    = 127
+)
+
+// KeyAlgorithm is an algorithm of generated key pair.
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA     KeyAlgorithm = "RSA"
+	KeyAlgorithmECDSA   KeyAlgorithm = "EC"
+	KeyAlgorithmEd25519 KeyAlgorithm = "Ed25519"
+)
+
+// KeyPairOptions describes key pair and self-signed certificate to generate.
+type KeyPairOptions struct {
+	// Algorithm of the key pair, RSA is used if empty.
+	Algorithm KeyAlgorithm
+	// KeySize is RSA modulus size or ECDSA curve size in bits. Ignored for Ed25519.
+	// Defaults to 2048 for RSA and 256 for ECDSA.
+	KeySize int
+
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []*url.URL
+
+	// NotBefore defaults to the current time.
+	NotBefore time.Time
+	// Validity defaults to 90 days.
+	Validity time.Duration
+
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	IsCA        bool
+
+	// SignatureAlgorithm is chosen based on the key algorithm if unknown.
+	SignatureAlgorithm x509.SignatureAlgorithm
+}
+
+// GenerateKeyPair generates a key pair and a self-signed certificate described by opts
+// and adds them into keystore as PrivateKeyEntry by alias encrypted with password.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) GenerateKeyPair(alias string, opts KeyPairOptions, password []byte) error {
+	pke, err := GeneratePrivateKeyEntry(ks.r, opts)
+	if err != nil {
+		return err
+	}
+
+	defer zeroing(pke.PrivateKey)
+
+	if err := ks.SetPrivateKeyEntry(alias, pke, password); err != nil {
+		return fmt.Errorf("set private key entry: %w", err)
+	}
+
+	return nil
+}
+
+// GeneratePrivateKeyEntry generates a key pair and a self-signed certificate described by opts.
+// Returned entry holds the PKCS#8 encoded private key in plain text.
+func GeneratePrivateKeyEntry(rand io.Reader, opts KeyPairOptions) (PrivateKeyEntry, error) {
+	key, err := generateKey(rand, opts.Algorithm, opts.KeySize)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("generate key: %w", err)
+	}
+
+	serialNumber, err := randomSerialNumber(rand)
+	if err != nil {
+		return PrivateKeyEntry{}, err
+	}
+
+	notBefore := opts.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+
+	validity := opts.Validity
+	if validity == 0 {
+		validity = defaultValidity
+	}
+
+	keyUsage := opts.KeyUsage
+	if opts.IsCA {
+		keyUsage |= x509.KeyUsageCertSign
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               opts.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+		EmailAddresses:        opts.EmailAddresses,
+		URIs:                  opts.URIs,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           opts.ExtKeyUsage,
+		IsCA:                  opts.IsCA,
+		BasicConstraintsValid: opts.IsCA,
+		SignatureAlgorithm:    opts.SignatureAlgorithm,
+	}
+
+	certDER, err := x509.CreateCertificate(rand, template, template, key.Public(), key)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	pke := PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   keyDER,
+		CertificateChain: []Certificate{
+			{
+				Type:    X509CertificateType,
+				Content: certDER,
+			},
+		},
+	}
+
+	return pke, nil
+}
+
+func generateKey(rand io.Reader, algorithm KeyAlgorithm, keySize int) (crypto.Signer, error) {
+	switch algorithm {
+	case KeyAlgorithmRSA, "":
+		if keySize == 0 {
+			keySize = defaultRSAKeySize
+		}
+
+		return rsa.GenerateKey(rand, keySize)
+	case KeyAlgorithmECDSA:
+		if keySize == 0 {
+			keySize = defaultECDSAKeySize
+		}
+
+		var curve elliptic.Curve
+
+		switch keySize {
+		case 256: //nolint:gomnd,mnd
+			curve = elliptic.P256()
+		case 384: //nolint:gomnd,mnd
+			curve = elliptic.P384()
+		case 521: //nolint:gomnd,mnd
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("got unsupported curve size %d", keySize)
+		}
+
+		return ecdsa.GenerateKey(curve, rand)
+	case KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand)
+
+		return key, err
+	default:
+		return nil, fmt.Errorf("%q: %w", algorithm, ErrUnsupportedKeyAlgorithm)
+	}
+}
+
+func randomSerialNumber(r io.Reader) (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), serialNumberBits)
+
+	serialNumber, err := rand.Int(r, limit)
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	return serialNumber.Add(serialNumber, big.NewInt(1)), nil
+}
+
+// parsePrivateKey parses PKCS#8 encoded private key which can be used for signing.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%T: %w", key, ErrUnsupportedKeyAlgorithm)
+	}
+
+	return signer, nil
+}
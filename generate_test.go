@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	table := []struct {
+		algorithm KeyAlgorithm
+		keySize   int
+	}{
+		{algorithm: KeyAlgorithmRSA, keySize: 1024},
+		{algorithm: KeyAlgorithmECDSA},
+		{algorithm: KeyAlgorithmECDSA, keySize: 384},
+		{algorithm: KeyAlgorithmEd25519},
+	}
+
+	password := []byte("password")
+	notBefore := time.Now().Truncate(time.Second)
+
+	for _, tt := range table {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			ks := New()
+
+			opts := KeyPairOptions{
+				Algorithm:   tt.algorithm,
+				KeySize:     tt.keySize,
+				Subject:     pkix.Name{CommonName: "localhost", Organization: []string{"Example"}},
+				DNSNames:    []string{"localhost"},
+				IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+				NotBefore:   notBefore,
+				Validity:    24 * time.Hour,
+				KeyUsage:    x509.KeyUsageDigitalSignature,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}
+
+			err := ks.GenerateKeyPair("alias", opts, password)
+			require.NoError(t, err)
+
+			pke, err := ks.GetPrivateKeyEntry("alias", password)
+			require.NoError(t, err)
+			require.Len(t, pke.CertificateChain, 1)
+
+			key, err := x509.ParsePKCS8PrivateKey(pke.PrivateKey)
+			require.NoError(t, err)
+
+			cert, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+			require.NoError(t, err)
+
+			signer, ok := key.(crypto.Signer)
+			require.True(t, ok)
+			assert.Equal(t, signer.Public(), cert.PublicKey, "certificate must hold generated public key")
+			assert.Equal(t, "CN=localhost,O=Example", cert.Subject.String())
+			assert.Equal(t, cert.Subject.String(), cert.Issuer.String())
+			assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+			assert.True(t, cert.IPAddresses[0].Equal(net.IPv4(127, 0, 0, 1)))
+			assert.True(t, notBefore.Equal(cert.NotBefore))
+			assert.True(t, notBefore.Add(24*time.Hour).Equal(cert.NotAfter))
+			assert.Equal(t, x509.KeyUsageDigitalSignature, cert.KeyUsage)
+			assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+			assert.False(t, cert.IsCA)
+			require.NoError(t, cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature))
+		})
+	}
+}
+
+func TestGenerateKeyPairCA(t *testing.T) {
+	pke, err := GeneratePrivateKeyEntry(rand.Reader, KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "ca"},
+		IsCA:      true,
+	})
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+	require.NoError(t, err)
+
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, x509.KeyUsageCertSign, cert.KeyUsage&x509.KeyUsageCertSign)
+	assert.Equal(t, defaultValidity, cert.NotAfter.Sub(cert.NotBefore))
+}
+
+func TestGenerateKeyPairUnsupportedAlgorithm(t *testing.T) {
+	ks := New()
+
+	err := ks.GenerateKeyPair("alias", KeyPairOptions{Algorithm: "DSA"}, []byte("password"))
+	require.ErrorIs(t, err, ErrUnsupportedKeyAlgorithm)
+
+	err = ks.GenerateKeyPair("alias", KeyPairOptions{Algorithm: KeyAlgorithmECDSA, KeySize: 128}, []byte("password"))
+	require.Error(t, err)
+	assert.Empty(t, ks.Aliases())
+}
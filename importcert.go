@@ -0,0 +1,216 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// InstallCertificateReply replaces certificate chain of PrivateKeyEntry from the keystore by the alias
+// with the certificate authority reply. The reply is PEM or DER encoded certificates or PKCS#7 structure.
+// The leaf certificate of the reply must hold the public key of the entry. The chain is completed
+// from the reply and the trusted certificate entries of the keystore. The encrypted private key is
+// kept as is, the password is only used to get the public key if the entry has no certificate chain,
+// nil password may be passed otherwise.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) InstallCertificateReply(alias string, reply []byte, password []byte) error {
+	alias = ks.convertAlias(alias)
+
+	e, ok := ks.m[alias]
+	if !ok {
+		return ErrEntryNotFound
+	}
+
+	pke, ok := e.(PrivateKeyEntry)
+	if !ok {
+		return ErrWrongEntryType
+	}
+
+	certs, err := parseCertificateReply(reply)
+	if err != nil {
+		return fmt.Errorf("parse certificate reply: %w", err)
+	}
+
+	publicKey, err := ks.entryPublicKey(pke, password)
+	if err != nil {
+		return err
+	}
+
+	var leaf *x509.Certificate
+
+	for _, cert := range certs {
+		if publicKeysEqual(publicKey, cert.PublicKey) {
+			leaf = cert
+
+			break
+		}
+	}
+
+	if leaf == nil {
+		return fmt.Errorf("find certificate of the entry in reply: %w", ErrPublicKeyMismatch)
+	}
+
+	trusted, err := ks.TrustedCertificates()
+	if err != nil {
+		return err
+	}
+
+	chain := buildCertificateChain(leaf, append(certs, trusted...))
+
+	pke.CertificateChain = make([]Certificate, 0, len(chain))
+	for _, cert := range chain {
+		pke.CertificateChain = append(pke.CertificateChain, Certificate{
+			Type:    X509CertificateType,
+			Content: cert.Raw,
+		})
+	}
+
+	ks.m[alias] = pke
+
+	return nil
+}
+
+// entryPublicKey returns public key of the entry taken from the first certificate of the chain or
+// from the private key decrypted with password if the chain is empty.
+func (ks KeyStore) entryPublicKey(pke PrivateKeyEntry, password []byte) (crypto.PublicKey, error) {
+	if len(pke.CertificateChain) > 0 {
+		cert, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+
+		return cert.PublicKey, nil
+	}
+
+	if password == nil {
+		return nil, errors.New("got entry without certificate chain, password is required")
+	}
+
+	dpk, err := ks.keyProtector().decrypt(pke.PrivateKey, password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer destroySecret(dpk)
+
+	key, err := parsePrivateKey(dpk)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Public(), nil
+}
+
+// parseCertificateReply parses PEM encoded certificates and PKCS#7 structures or DER encoded
+// certificate sequence or PKCS#7 structure. Text around PEM blocks is ignored.
+func parseCertificateReply(reply []byte) ([]*x509.Certificate, error) {
+	if len(bytes.TrimSpace(reply)) == 0 {
+		return nil, ErrEmptyCertificateReply
+	}
+
+	var (
+		certs []*x509.Certificate
+		found bool
+	)
+
+	for rest := reply; ; {
+		var b *pem.Block
+
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+
+		found = true
+
+		switch b.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(b.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse certificate: %w", err)
+			}
+
+			certs = append(certs, cert)
+		case "PKCS7":
+			pkcs7Certs, err := parsePKCS7Certificates(b.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, pkcs7Certs...)
+		}
+	}
+
+	// DER is not trimmed, its last bytes may look like whitespace.
+	if !found {
+		if certs, err := x509.ParseCertificates(reply); err == nil {
+			return certs, nil
+		}
+
+		return parsePKCS7Certificates(reply)
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrEmptyCertificateReply
+	}
+
+	return certs, nil
+}
+
+// buildCertificateChain orders certificate chain from the leaf to the root picking issuers from candidates.
+// The chain ends with a self-signed certificate or with a certificate which issuer is not found.
+func buildCertificateChain(leaf *x509.Certificate, candidates []*x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{leaf}
+
+	for current := leaf; !isSelfSigned(current); {
+		var issuer *x509.Certificate
+
+		for _, candidate := range candidates {
+			if !bytes.Equal(candidate.RawSubject, current.RawIssuer) || containsCertificate(chain, candidate) {
+				continue
+			}
+
+			if current.CheckSignatureFrom(candidate) == nil {
+				issuer = candidate
+
+				break
+			}
+		}
+
+		if issuer == nil {
+			break
+		}
+
+		chain = append(chain, issuer)
+		current = issuer
+	}
+
+	return chain
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func containsCertificate(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+
+	return ok && k.Equal(b)
+}
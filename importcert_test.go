@@ -0,0 +1,220 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallCertificateReply(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.GenerateKeyPair("alias", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "localhost"},
+	}, password)
+	require.NoError(t, err)
+
+	publicKey := entryTestPublicKey(t, ks, "alias")
+	root, intermediate, leaf := issueTestChain(t, publicKey)
+
+	err = ks.SetTrustedCertificateEntry("root", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: root.Raw},
+	})
+	require.NoError(t, err)
+
+	encryptedKey := ks.m["alias"].(PrivateKeyEntry).PrivateKey
+
+	var reply bytes.Buffer
+
+	for _, cert := range []*x509.Certificate{intermediate, leaf} {
+		require.NoError(t, pem.Encode(&reply, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+
+	err = ks.InstallCertificateReply("alias", reply.Bytes(), nil)
+	require.NoError(t, err)
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain("alias")
+	require.NoError(t, err)
+	assert.Equal(t, []Certificate{
+		{Type: X509CertificateType, Content: leaf.Raw},
+		{Type: X509CertificateType, Content: intermediate.Raw},
+		{Type: X509CertificateType, Content: root.Raw},
+	}, chain)
+	assert.Equal(t, encryptedKey, ks.m["alias"].(PrivateKeyEntry).PrivateKey)
+
+	_, err = ks.GetPrivateKeyEntry("alias", password)
+	require.NoError(t, err)
+}
+
+func TestInstallCertificateReplyPKCS7(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.SetPrivateKeyEntry("alias", PrivateKeyEntry{PrivateKey: readPrivateKey(t)}, password)
+	require.NoError(t, err)
+
+	key, err := parsePrivateKey(readPrivateKey(t))
+	require.NoError(t, err)
+
+	root, intermediate, leaf := issueTestChain(t, key.Public())
+	reply := marshalTestPKCS7(t, root, leaf, intermediate)
+
+	err = ks.InstallCertificateReply("alias", reply, nil)
+	require.Error(t, err, "password is required for entry without certificate chain")
+
+	err = ks.InstallCertificateReply("alias", reply, password)
+	require.NoError(t, err)
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain("alias")
+	require.NoError(t, err)
+	assert.Equal(t, []Certificate{
+		{Type: X509CertificateType, Content: leaf.Raw},
+		{Type: X509CertificateType, Content: intermediate.Raw},
+		{Type: X509CertificateType, Content: root.Raw},
+	}, chain)
+
+	pemReply := pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: reply})
+
+	err = ks.InstallCertificateReply("alias", pemReply, nil)
+	require.NoError(t, err)
+}
+
+func TestInstallCertificateReplyErrors(t *testing.T) {
+	ks := New()
+	password := []byte("password")
+
+	err := ks.GenerateKeyPair("alias", KeyPairOptions{Algorithm: KeyAlgorithmECDSA}, password)
+	require.NoError(t, err)
+
+	err = ks.SetTrustedCertificateEntry("trusted", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	})
+	require.NoError(t, err)
+
+	_, _, leaf := issueTestChain(t, generateTestKey(t).Public())
+
+	err = ks.InstallCertificateReply("alias", leaf.Raw, nil)
+	require.ErrorIs(t, err, ErrPublicKeyMismatch)
+
+	err = ks.InstallCertificateReply("alias", []byte("  "), nil)
+	require.ErrorIs(t, err, ErrEmptyCertificateReply)
+
+	err = ks.InstallCertificateReply("missing", leaf.Raw, nil)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	err = ks.InstallCertificateReply("trusted", leaf.Raw, nil)
+	require.ErrorIs(t, err, ErrWrongEntryType)
+}
+
+func TestParseCertificateReplyTrailingWhitespaceByte(t *testing.T) {
+	der, err := os.ReadFile("./testdata/cert_trailing_newline.der")
+	require.NoError(t, err)
+	require.Equal(t, byte('\n'), der[len(der)-1])
+
+	certs, err := parseCertificateReply(der)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.Equal(t, der, certs[0].Raw)
+}
+
+func TestParseCertificateReplyLeadingText(t *testing.T) {
+	cert := readCertificate(t)
+	reply := append([]byte("subject=CN = localhost\nissuer=CN = localhost\n"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+
+	certs, err := parseCertificateReply(reply)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	assert.Equal(t, cert, certs[0].Raw)
+
+	_, err = parseCertificateReply([]byte("subject=CN = localhost\n"))
+	require.Error(t, err)
+}
+
+func entryTestPublicKey(t *testing.T, ks KeyStore, alias string) any {
+	t.Helper()
+
+	chain, err := ks.GetPrivateKeyEntryCertificateChain(alias)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(chain[0].Content)
+	require.NoError(t, err)
+
+	return cert.PublicKey
+}
+
+func issueTestChain(t *testing.T, publicKey any) (*x509.Certificate, *x509.Certificate, *x509.Certificate) {
+	t.Helper()
+
+	now := time.Now()
+
+	rootKey := generateTestKey(t)
+	root := createTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, rootKey.Public(), rootKey)
+
+	intermediateKey := generateTestKey(t)
+	intermediate := createTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, root, intermediateKey.Public(), rootKey)
+
+	leaf := createTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "localhost"},
+		DNSNames:  []string{"localhost"},
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(time.Hour),
+	}, intermediate, publicKey, intermediateKey)
+
+	return root, intermediate, leaf
+}
+
+func marshalTestPKCS7(t *testing.T, certs ...*x509.Certificate) []byte {
+	t.Helper()
+
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+
+	data, err := asn1.Marshal(contentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})
+	require.NoError(t, err)
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      asn1.RawValue{FullBytes: data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+	})
+	require.NoError(t, err)
+
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	require.NoError(t, err)
+
+	return der
+}
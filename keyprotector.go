@@ -19,7 +19,19 @@ type keyInfo struct {
 	PrivateKey []byte
 }
 
-func decrypt(data []byte, password []byte) ([]byte, error) {
+// keyProtector encrypts private keys of PrivateKeyEntry with options of the keystore.
+type keyProtector struct {
+	legacyPasswordEncoding bool
+	lockedMemory           bool
+}
+
+func (ks KeyStore) keyProtector() keyProtector {
+	return keyProtector{legacyPasswordEncoding: ks.legacyPasswordEncoding, lockedMemory: ks.lockedMemory}
+}
+
+// decrypt returns plain key copied out of the buffer from newSecretBuffer, so it is never unmapped while
+// it is used. All intermediate secrets are filled with zeros.
+func (p keyProtector) decrypt(data []byte, password []byte) ([]byte, error) {
 	var keyInfo keyInfo
 
 	asn1Rest, err := asn1.Unmarshal(data, &keyInfo)
@@ -37,24 +49,33 @@ func decrypt(data []byte, password []byte) ([]byte, error) {
 
 	md := sha1.New()
 
-	passwordBytes := passwordBytes(password)
+	passwordBytes := passwordBytes(password, p.legacyPasswordEncoding)
 	defer zeroing(passwordBytes)
 
-	salt := make([]byte, saltLen)
-	copy(salt, keyInfo.PrivateKey)
 	encryptedKeyLen := len(keyInfo.PrivateKey) - saltLen - md.Size()
+	if encryptedKeyLen < 0 {
+		return nil, errors.New("got short encrypted key")
+	}
+
 	numRounds := encryptedKeyLen / md.Size()
 
 	if encryptedKeyLen%md.Size() != 0 {
 		numRounds++
 	}
 
-	encryptedKey := make([]byte, encryptedKeyLen)
-	copy(encryptedKey, keyInfo.PrivateKey[saltLen:])
+	encryptedKey := keyInfo.PrivateKey[saltLen : saltLen+encryptedKeyLen]
 
-	xorKey := make([]byte, encryptedKeyLen)
+	xorKey, err := newSecretBuffer(encryptedKeyLen, p.lockedMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	defer destroySecret(xorKey)
 
-	digest := salt
+	digest := make([]byte, saltLen, md.Size())
+	copy(digest, keyInfo.PrivateKey)
+
+	defer zeroing(digest)
 
 	for i, xorOffset := 0, 0; i < numRounds; i++ {
 		if _, err := md.Write(passwordBytes); err != nil {
@@ -65,13 +86,19 @@ func decrypt(data []byte, password []byte) ([]byte, error) {
 			return nil, fmt.Errorf("update digest with digest from previous round on %d round: %w", i, err)
 		}
 
-		digest = md.Sum(nil)
+		digest = md.Sum(digest[:0])
 		md.Reset()
 		copy(xorKey[xorOffset:], digest)
 		xorOffset += md.Size()
 	}
 
-	plainKey := make([]byte, encryptedKeyLen)
+	plainKey, err := newSecretBuffer(encryptedKeyLen, p.lockedMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	defer destroySecret(plainKey)
+
 	for i := 0; i < len(plainKey); i++ {
 		plainKey[i] = encryptedKey[i] ^ xorKey[i]
 	}
@@ -84,21 +111,22 @@ func decrypt(data []byte, password []byte) ([]byte, error) {
 		return nil, fmt.Errorf("update digest with plain key: %w", err)
 	}
 
-	digest = md.Sum(nil)
+	digest = md.Sum(digest[:0])
 	md.Reset()
 
 	digestOffset := saltLen + encryptedKeyLen
 	if !bytes.Equal(digest, keyInfo.PrivateKey[digestOffset:digestOffset+len(digest)]) {
-		return nil, errors.New("got invalid digest")
+		return nil, fmt.Errorf("got invalid digest: %w", ErrIncorrectPassword)
 	}
 
-	return plainKey, nil
+	return append([]byte(nil), plainKey...), nil
 }
 
-func encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
+// encrypt returns encrypted key, the key stream is kept in the buffer from newSecretBuffer.
+func (p keyProtector) encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
 	md := sha1.New()
 
-	passwordBytes := passwordBytes(password)
+	passwordBytes := passwordBytes(password, p.legacyPasswordEncoding)
 	defer zeroing(passwordBytes)
 
 	plainKeyLen := len(plainKey)
@@ -113,9 +141,17 @@ func encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
 		return nil, fmt.Errorf("read random bytes: %w", err)
 	}
 
-	xorKey := make([]byte, plainKeyLen)
+	xorKey, err := newSecretBuffer(plainKeyLen, p.lockedMemory)
+	if err != nil {
+		return nil, err
+	}
 
-	digest := salt
+	defer destroySecret(xorKey)
+
+	digest := make([]byte, saltLen, md.Size())
+	copy(digest, salt)
+
+	defer zeroing(digest)
 
 	for i, xorOffset := 0, 0; i < numRounds; i++ {
 		if _, err := md.Write(passwordBytes); err != nil {
@@ -126,22 +162,21 @@ func encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
 			return nil, fmt.Errorf("update digest with digest from prevous round on %d round: %w", i, err)
 		}
 
-		digest = md.Sum(nil)
+		digest = md.Sum(digest[:0])
 		md.Reset()
 		copy(xorKey[xorOffset:], digest)
 		xorOffset += md.Size()
 	}
 
-	tmpKey := make([]byte, plainKeyLen)
-	for i := range plainKeyLen {
-		tmpKey[i] = plainKey[i] ^ xorKey[i]
-	}
-
 	encryptedKey := make([]byte, saltLen+plainKeyLen+md.Size())
 	encryptedKeyOffset := 0
 	copy(encryptedKey[encryptedKeyOffset:], salt)
 	encryptedKeyOffset += saltLen
-	copy(encryptedKey[encryptedKeyOffset:], tmpKey)
+
+	for i := range plainKeyLen {
+		encryptedKey[encryptedKeyOffset+i] = plainKey[i] ^ xorKey[i]
+	}
+
 	encryptedKeyOffset += plainKeyLen
 
 	if _, err := md.Write(passwordBytes); err != nil {
@@ -152,7 +187,7 @@ func encrypt(rand io.Reader, plainKey []byte, password []byte) ([]byte, error) {
 		return nil, fmt.Errorf("udpate digest with plain key: %w", err)
 	}
 
-	digest = md.Sum(nil)
+	digest = md.Sum(digest[:0])
 	md.Reset()
 	copy(encryptedKey[encryptedKeyOffset:], digest)
 
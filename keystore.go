@@ -13,15 +13,26 @@ import (
 )
 
 var (
-	ErrEntryNotFound           = errors.New("entry not found")
-	ErrWrongEntryType          = errors.New("wrong entry type")
-	ErrEmptyPrivateKey         = errors.New("empty private key")
-	ErrEmptyCertificateType    = errors.New("empty certificate type")
-	ErrEmptyCertificateContent = errors.New("empty certificate content")
-	ErrShortPassword           = errors.New("short password")
+	ErrEntryNotFound              = errors.New("entry not found")
+	ErrWrongEntryType             = errors.New("wrong entry type")
+	ErrEmptyPrivateKey            = errors.New("empty private key")
+	ErrEmptySecretKey             = errors.New("empty secret key")
+	ErrEmptyCertificateType       = errors.New("empty certificate type")
+	ErrEmptyCertificateContent    = errors.New("empty certificate content")
+	ErrShortPassword              = errors.New("short password")
+	ErrUnsupportedKeyAlgorithm    = errors.New("unsupported key algorithm")
+	ErrPublicKeyMismatch          = errors.New("public key mismatch")
+	ErrEmptyCertificateReply      = errors.New("empty certificate reply")
+	ErrIncorrectPassword          = errors.New("incorrect password")
+	ErrAliasExists                = errors.New("alias exists")
+	ErrPasswordPolicy             = errors.New("password policy violation")
+	ErrUnsupportedCertificateType = errors.New("unsupported certificate type")
+	ErrUnorderedCertificateChain  = errors.New("unordered certificate chain")
+	ErrPasswordEncodingMismatch   = errors.New("password encoding mismatch")
 )
 
 // KeyStore is a mapping of alias to pointer to PrivateKeyEntry or TrustedCertificateEntry.
+// Keystores loaded from BouncyCastle formats may also hold SecretKeyEntry and SealedEntry.
 type KeyStore struct {
 	m map[string]interface{}
 	r io.Reader
@@ -29,6 +40,13 @@ type KeyStore struct {
 	ordered        bool
 	caseExact      bool
 	minPasswordLen int
+	policy         PasswordPolicy
+	lockedMemory   bool
+
+	legacyPasswordEncoding bool
+	version1               bool
+	strictValidation       bool
+	pkcs12WithoutMAC       bool
 }
 
 // PrivateKeyEntry is an entry for private keys and associated certificates.
@@ -36,12 +54,38 @@ type PrivateKeyEntry struct {
 	CreationTime     time.Time
 	PrivateKey       []byte
 	CertificateChain []Certificate
+	// Attributes are kept by PKCS#12 as attributes of the key bag and the leaf certificate bag
+	// and by BCFKS in the entry comment.
+	Attributes map[string]string
 }
 
 // TrustedCertificateEntry is an entry for certificates only.
 type TrustedCertificateEntry struct {
 	CreationTime time.Time
 	Certificate  Certificate
+	// Attributes are kept by PKCS#12 as attributes of the certificate bag and by BCFKS in the entry comment.
+	Attributes map[string]string
+}
+
+// SecretKeyEntry is an entry for secret keys of BouncyCastle keystores. The key is not encrypted.
+// Algorithm and Format are empty if the keystore holds the key as opaque bytes.
+// JKS keystore can not hold SecretKeyEntry.
+type SecretKeyEntry struct {
+	CreationTime     time.Time
+	Algorithm        string
+	Format           string
+	Key              []byte
+	CertificateChain []Certificate
+	// Attributes are kept by BCFKS in the entry comment.
+	Attributes map[string]string
+}
+
+// SealedEntry is an entry for keys BouncyCastle keystores encrypt with the key password.
+// Use UnsealEntry to decrypt it. JKS keystore can not hold SealedEntry.
+type SealedEntry struct {
+	CreationTime     time.Time
+	Content          []byte
+	CertificateChain []Certificate
 }
 
 // Certificate describes type of certificate.
@@ -67,11 +111,52 @@ func WithMinPasswordLen(minPasswordLen int) Option {
 	return func(ks *KeyStore) { ks.minPasswordLen = minPasswordLen }
 }
 
+// WithPasswordPolicy sets policy option to policy argument value. Store checks the store password
+// and SetPrivateKeyEntry checks key passwords with it, including the ones Load methods of other
+// formats encrypt entries with. Violations are returned as *PasswordPolicyError.
+func WithPasswordPolicy(policy PasswordPolicy) Option {
+	return func(ks *KeyStore) { ks.policy = policy }
+}
+
 // WithCustomRandomNumberGenerator sets a random generator used to generate salt when encrypting private keys.
 func WithCustomRandomNumberGenerator(r io.Reader) Option {
 	return func(ks *KeyStore) { ks.r = r }
 }
 
+// WithLockedMemory sets lockedMemory option to true. Private keys are encrypted and decrypted in memory locked
+// with mlock on Linux, so intermediate secrets are not swapped out, and the memory is unlocked before the
+// decrypted key is returned. Heap memory is used on other platforms or if the limit of locked memory is reached.
+func WithLockedMemory() Option {
+	return func(ks *KeyStore) { ks.lockedMemory = true }
+}
+
+// WithLegacyPasswordEncoding sets legacyPasswordEncoding option to true. Every byte of store and key
+// passwords is taken as a character, the way older versions of the library did, instead of decoding
+// passwords as UTF-8. Use it for keystores with non-ASCII passwords written by older versions.
+func WithLegacyPasswordEncoding() Option {
+	return func(ks *KeyStore) { ks.legacyPasswordEncoding = true }
+}
+
+// WithVersion1 sets version1 option to true. Store writes version 1 of the format which has no certificate types
+// instead of the latest one. Store returns ErrUnsupportedCertificateType if any certificate is not X.509.
+func WithVersion1() Option {
+	return func(ks *KeyStore) { ks.version1 = true }
+}
+
+// WithStrictValidation sets strictValidation option to true. SetPrivateKeyEntry and SetTrustedCertificateEntry
+// parse the private key and the certificates, check that the first certificate of the chain matches
+// the private key and that every certificate of the chain is issued and signed by the next one.
+// ErrUnorderedCertificateChain is returned if the issuer of a certificate is not the next one.
+func WithStrictValidation() Option {
+	return func(ks *KeyStore) { ks.strictValidation = true }
+}
+
+// WithPKCS12WithoutMAC sets pkcs12WithoutMAC option to true. LoadPKCS12 accepts PKCS#12 without MAC,
+// so integrity of its content is not checked and incorrect password is detected only by failed decryption.
+func WithPKCS12WithoutMAC() Option {
+	return func(ks *KeyStore) { ks.pkcs12WithoutMAC = true }
+}
+
 // New returns new initialized instance of the KeyStore.
 func New(options ...Option) KeyStore {
 	ks := KeyStore{
@@ -86,19 +171,35 @@ func New(options ...Option) KeyStore {
 	return ks
 }
 
-// Store signs keystore using password and writes its representation into w
+// Store signs keystore using password and writes its representation into w.
+// JKS can not hold entry attributes, Store drops them.
 // It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) Store(w io.Writer, password []byte) error {
 	if len(password) < ks.minPasswordLen {
 		return fmt.Errorf("password must be at least %d characters: %w", ks.minPasswordLen, ErrShortPassword)
 	}
 
+	if err := ks.checkStorePassword(password); err != nil {
+		return err
+	}
+
+	version := version02
+	if ks.version1 {
+		version = version01
+	}
+
+	entries, err := ks.normalizeEntries(version)
+	if err != nil {
+		return err
+	}
+
 	e := encoder{
-		w: w,
-		h: sha1.New(),
+		w:       w,
+		h:       sha1.New(),
+		version: version,
 	}
 
-	passwordBytes := passwordBytes(password)
+	passwordBytes := passwordBytes(password, ks.legacyPasswordEncoding)
 	defer zeroing(passwordBytes)
 
 	if _, err := e.h.Write(passwordBytes); err != nil {
@@ -112,17 +213,16 @@ func (ks KeyStore) Store(w io.Writer, password []byte) error {
 	if err := e.writeUint32(magic); err != nil {
 		return fmt.Errorf("write magic: %w", err)
 	}
-	// always write latest version
-	if err := e.writeUint32(version02); err != nil {
+	if err := e.writeUint32(version); err != nil {
 		return fmt.Errorf("write version: %w", err)
 	}
 
-	if err := e.writeUint32(uint32(len(ks.m))); err != nil { //nolint:gosec
+	if err := e.writeUint32(uint32(len(entries))); err != nil { //nolint:gosec
 		return fmt.Errorf("write number of entries: %w", err)
 	}
 
 	for _, alias := range ks.Aliases() {
-		switch typedEntry := ks.m[alias].(type) {
+		switch typedEntry := entries[alias].(type) {
 		case PrivateKeyEntry:
 			if err := e.writePrivateKeyEntry(alias, typedEntry); err != nil {
 				return fmt.Errorf("write private key entry: %w", err)
@@ -151,7 +251,7 @@ func (ks KeyStore) Load(r io.Reader, password []byte) error {
 		h: sha1.New(),
 	}
 
-	passwordBytes := passwordBytes(password)
+	passwordBytes := passwordBytes(password, ks.legacyPasswordEncoding)
 	defer zeroing(passwordBytes)
 
 	if _, err := d.h.Write(passwordBytes); err != nil {
@@ -162,6 +262,78 @@ func (ks KeyStore) Load(r io.Reader, password []byte) error {
 		return fmt.Errorf("update digest with whitener message: %w", err)
 	}
 
+	if err := ks.readEntries(d); err != nil {
+		return err
+	}
+
+	computedDigest := d.h.Sum(nil)
+	defer zeroing(computedDigest)
+
+	actualDigest, err := d.readBytes(uint32(d.h.Size())) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("read digest: %w", err)
+	}
+
+	if !bytes.Equal(actualDigest, computedDigest) {
+		return fmt.Errorf("got invalid digest: %w", ErrIncorrectPassword)
+	}
+
+	return nil
+}
+
+// normalizeEntries returns entries with certificates normalized the way Certificate.normalize does.
+// Version 1 of the format can hold X.509 certificates only.
+func (ks KeyStore) normalizeEntries(version uint32) (map[string]interface{}, error) {
+	entries := make(map[string]interface{}, len(ks.m))
+
+	for _, alias := range ks.Aliases() {
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			chain, err := normalizeCertificates(typedEntry.CertificateChain, version)
+			if err != nil {
+				return nil, fmt.Errorf("check certificate chain of %q: %w", alias, err)
+			}
+
+			typedEntry.CertificateChain = chain
+			entries[alias] = typedEntry
+		case TrustedCertificateEntry:
+			certs, err := normalizeCertificates([]Certificate{typedEntry.Certificate}, version)
+			if err != nil {
+				return nil, fmt.Errorf("check certificate of %q: %w", alias, err)
+			}
+
+			typedEntry.Certificate = certs[0]
+			entries[alias] = typedEntry
+		default:
+			return nil, errors.New("got invalid entry")
+		}
+	}
+
+	return entries, nil
+}
+
+func normalizeCertificates(certs []Certificate, version uint32) ([]Certificate, error) {
+	normalized := make([]Certificate, len(certs))
+
+	for i, cert := range certs {
+		if version == version01 && !isX509CertificateType(cert.Type) {
+			return nil, fmt.Errorf("check %d certificate type %q for version 1: %w",
+				i, cert.Type, ErrUnsupportedCertificateType)
+		}
+
+		c, err := cert.normalize()
+		if err != nil {
+			return nil, fmt.Errorf("check %d certificate: %w", i, err)
+		}
+
+		normalized[i] = c
+	}
+
+	return normalized, nil
+}
+
+// readEntries reads keystore representation up to the digest into the keystore.
+func (ks KeyStore) readEntries(d decoder) error {
 	readMagic, err := d.readUint32()
 	if err != nil {
 		return fmt.Errorf("read magic: %w", err)
@@ -190,24 +362,13 @@ func (ks KeyStore) Load(r io.Reader, password []byte) error {
 		ks.m[alias] = entry
 	}
 
-	computedDigest := d.h.Sum(nil)
-
-	actualDigest, err := d.readBytes(uint32(d.h.Size())) //nolint:gosec
-	if err != nil {
-		return fmt.Errorf("read digest: %w", err)
-	}
-
-	if !bytes.Equal(actualDigest, computedDigest) {
-		return errors.New("got invalid digest")
-	}
-
 	return nil
 }
 
 // SetPrivateKeyEntry adds PrivateKeyEntry into keystore by alias encrypted with password.
 // It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) SetPrivateKeyEntry(alias string, entry PrivateKeyEntry, password []byte) error {
-	if err := entry.validate(); err != nil {
+	if err := ks.validatePrivateKeyEntry(entry); err != nil {
 		return fmt.Errorf("validate private key entry: %w", err)
 	}
 
@@ -215,7 +376,11 @@ func (ks KeyStore) SetPrivateKeyEntry(alias string, entry PrivateKeyEntry, passw
 		return fmt.Errorf("password must be at least %d characters: %w", ks.minPasswordLen, ErrShortPassword)
 	}
 
-	epk, err := encrypt(ks.r, entry.PrivateKey, password)
+	if err := ks.checkKeyPassword(alias, password); err != nil {
+		return err
+	}
+
+	epk, err := ks.keyProtector().encrypt(ks.r, entry.PrivateKey, password)
 	if err != nil {
 		return fmt.Errorf("encrypt private key: %w", err)
 	}
@@ -228,6 +393,7 @@ func (ks KeyStore) SetPrivateKeyEntry(alias string, entry PrivateKeyEntry, passw
 }
 
 // GetPrivateKeyEntry returns PrivateKeyEntry from the keystore by the alias decrypted with the password.
+// It is strongly recommended to call PrivateKeyEntry.Destroy after usage.
 // It is strongly recommended to fill password slice with zero after usage.
 func (ks KeyStore) GetPrivateKeyEntry(alias string, password []byte) (PrivateKeyEntry, error) {
 	e, ok := ks.m[ks.convertAlias(alias)]
@@ -240,7 +406,7 @@ func (ks KeyStore) GetPrivateKeyEntry(alias string, password []byte) (PrivateKey
 		return PrivateKeyEntry{}, ErrWrongEntryType
 	}
 
-	dpk, err := decrypt(pke.PrivateKey, password)
+	dpk, err := ks.keyProtector().decrypt(pke.PrivateKey, password)
 	if err != nil {
 		return PrivateKeyEntry{}, fmt.Errorf("decrypt private key: %w", err)
 	}
@@ -275,7 +441,7 @@ func (ks KeyStore) IsPrivateKeyEntry(alias string) bool {
 
 // SetTrustedCertificateEntry adds TrustedCertificateEntry into keystore by alias.
 func (ks KeyStore) SetTrustedCertificateEntry(alias string, entry TrustedCertificateEntry) error {
-	if err := entry.validate(); err != nil {
+	if err := ks.validateTrustedCertificateEntry(entry); err != nil {
 		return fmt.Errorf("validate trusted certificate entry: %w", err)
 	}
 
@@ -306,6 +472,82 @@ func (ks KeyStore) IsTrustedCertificateEntry(alias string) bool {
 	return ok
 }
 
+// SetSecretKeyEntry adds SecretKeyEntry into the keystore by the alias.
+func (ks KeyStore) SetSecretKeyEntry(alias string, entry SecretKeyEntry) error {
+	if err := entry.validate(); err != nil {
+		return fmt.Errorf("validate secret key entry: %w", err)
+	}
+
+	ks.m[ks.convertAlias(alias)] = entry
+
+	return nil
+}
+
+// GetSecretKeyEntry returns SecretKeyEntry from the keystore by the alias.
+func (ks KeyStore) GetSecretKeyEntry(alias string) (SecretKeyEntry, error) {
+	e, ok := ks.m[ks.convertAlias(alias)]
+	if !ok {
+		return SecretKeyEntry{}, ErrEntryNotFound
+	}
+
+	ske, ok := e.(SecretKeyEntry)
+	if !ok {
+		return SecretKeyEntry{}, ErrWrongEntryType
+	}
+
+	return ske, nil
+}
+
+// IsSecretKeyEntry returns true if the keystore has SecretKeyEntry by the alias.
+func (ks KeyStore) IsSecretKeyEntry(alias string) bool {
+	_, ok := ks.m[ks.convertAlias(alias)].(SecretKeyEntry)
+
+	return ok
+}
+
+// GetSealedEntry returns SealedEntry from the keystore by the alias.
+func (ks KeyStore) GetSealedEntry(alias string) (SealedEntry, error) {
+	e, ok := ks.m[ks.convertAlias(alias)]
+	if !ok {
+		return SealedEntry{}, ErrEntryNotFound
+	}
+
+	se, ok := e.(SealedEntry)
+	if !ok {
+		return SealedEntry{}, ErrWrongEntryType
+	}
+
+	return se, nil
+}
+
+// IsSealedEntry returns true if the keystore has SealedEntry by the alias.
+func (ks KeyStore) IsSealedEntry(alias string) bool {
+	_, ok := ks.m[ks.convertAlias(alias)].(SealedEntry)
+
+	return ok
+}
+
+// Destroy fills private and secret keys of every entry with zeros and deletes all entries from the keystore.
+func (ks KeyStore) Destroy() {
+	for alias, e := range ks.m {
+		switch typedEntry := e.(type) {
+		case PrivateKeyEntry:
+			typedEntry.Destroy()
+		case SecretKeyEntry:
+			zeroing(typedEntry.Key)
+		case SealedEntry:
+			zeroing(typedEntry.Content)
+		}
+
+		delete(ks.m, alias)
+	}
+}
+
+// Destroy fills the private key with zeros. It is safe to call Destroy more than once.
+func (e PrivateKeyEntry) Destroy() {
+	destroySecret(e.PrivateKey)
+}
+
 // DeleteEntry deletes entry from the keystore.
 func (ks KeyStore) DeleteEntry(alias string) {
 	delete(ks.m, ks.convertAlias(alias))
@@ -352,6 +594,20 @@ func (e TrustedCertificateEntry) validate() error {
 	return e.Certificate.validate()
 }
 
+func (e SecretKeyEntry) validate() error {
+	if len(e.Key) == 0 {
+		return ErrEmptySecretKey
+	}
+
+	for i, c := range e.CertificateChain {
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("validate certificate %d in chain: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
 func (c Certificate) validate() error {
 	if len(c.Type) == 0 {
 		return ErrEmptyCertificateType
@@ -1,7 +1,11 @@
 package keystore
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
 	"encoding/pem"
+	"io"
 	"os"
 	"sort"
 	"testing"
@@ -144,6 +148,35 @@ func TestAliases(t *testing.T) {
 	assert.Equal(t, expectedAliases, actualAliases)
 }
 
+func TestDestroy(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("pke", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, []byte("password")))
+	require.NoError(t, ks.SetSecretKeyEntry("ske", SecretKeyEntry{
+		CreationTime: time.Now(),
+		Algorithm:    "AES",
+		Format:       "RAW",
+		Key:          []byte{1, 2, 3, 4},
+	}))
+
+	pke, err := ks.GetPrivateKeyEntry("pke", []byte("password"))
+	require.NoError(t, err)
+
+	pke.Destroy()
+	assert.Equal(t, make([]byte, len(pke.PrivateKey)), pke.PrivateKey)
+
+	encrypted := ks.m["pke"].(PrivateKeyEntry).PrivateKey
+	secret := ks.m["ske"].(SecretKeyEntry).Key
+
+	ks.Destroy()
+	assert.Empty(t, ks.Aliases())
+	assert.Equal(t, make([]byte, len(encrypted)), encrypted)
+	assert.Equal(t, []byte{0, 0, 0, 0}, secret)
+}
+
 func TestLoad(t *testing.T) {
 	password := []byte{'p', 'a', 's', 's', 'w', 'o', 'r', 'd'}
 	defer zeroing(password)
@@ -180,6 +213,206 @@ func TestLoad(t *testing.T) {
 	assert.Equal(t, decodedPK.Bytes, actualPKE.PrivateKey, "unexpected private key")
 }
 
+func TestUnicodePassword(t *testing.T) {
+	password := []byte("pässwörd密码😀")
+	utf16Password := []byte{
+		0, 'p', 0, 0xe4, 0, 's', 0, 's', 0, 'w', 0, 0xf6, 0, 'r', 0, 'd', 0x5b, 0xc6, 0x78, 0x01, 0xd8, 0x3d, 0xde, 0x00,
+	}
+
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("alias", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+	}, password))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, password))
+
+	body := buf.Bytes()[:buf.Len()-sha1.Size]
+	h := sha1.New()
+	h.Write(utf16Password)
+	h.Write(whitenerMessage)
+	h.Write(body)
+	assert.Equal(t, h.Sum(nil), buf.Bytes()[len(body):], "digest must be keyed with UTF-16BE password")
+
+	loaded := New()
+	require.NoError(t, loaded.Load(bytes.NewReader(buf.Bytes()), password))
+
+	pke, err := loaded.GetPrivateKeyEntry("alias", password)
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+
+	err = New(WithLegacyPasswordEncoding()).Load(bytes.NewReader(buf.Bytes()), password)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func TestLegacyPasswordEncoding(t *testing.T) {
+	password := []byte("pässwörd")
+
+	ks := New(WithLegacyPasswordEncoding())
+	require.NoError(t, ks.SetPrivateKeyEntry("alias", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+	}, password))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, password))
+
+	err := New().Load(bytes.NewReader(buf.Bytes()), password)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	loaded := New(WithLegacyPasswordEncoding())
+	require.NoError(t, loaded.Load(bytes.NewReader(buf.Bytes()), password))
+
+	_, err = loaded.GetPrivateKeyEntry("alias", password)
+	require.NoError(t, err)
+}
+
+// keystore_v1.jks is not written by keytool: JavaKeyStore of JDK 1.2 and later always writes version 2, and
+// version 1 was written only by JDK 1.1 which is not available. The fixture is converted from
+// keystore_keypass.jks written by keytool, TestVersion1Fixture repeats the conversion.
+func TestLoadVersion1(t *testing.T) {
+	content, err := os.ReadFile("./testdata/keystore_v1.jks")
+	require.NoError(t, err)
+
+	ks := New()
+	require.NoError(t, ks.Load(bytes.NewReader(content), []byte("password")))
+
+	expected := readKeyPasswordKeyStore(t)
+	assert.Equal(t, expected.Aliases(), ks.Aliases())
+
+	expectedPKE, err := expected.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	require.NoError(t, err)
+
+	pke, err := ks.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, expectedPKE, pke)
+
+	result, err := ks.Verify(bytes.NewReader(content), []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Version: 1, Entries: 1}, result)
+}
+
+// TestVersion1Fixture converts keystore_keypass.jks to version 1: the version is set to 1, certificate type
+// strings are removed, the encrypted key and certificates are kept as is and the digest is computed with
+// the store password again.
+func TestVersion1Fixture(t *testing.T) {
+	v2, err := os.ReadFile("./testdata/keystore_keypass.jks")
+	require.NoError(t, err)
+
+	r := bytes.NewReader(v2)
+	next := func(n int) []byte {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		require.NoError(t, err)
+
+		return b
+	}
+	nextBytes := func(lenSize int) []byte {
+		l := next(lenSize)
+		if lenSize == 2 {
+			return append(l, next(int(byteOrder.Uint16(l)))...)
+		}
+
+		return append(l, next(int(byteOrder.Uint32(l)))...)
+	}
+
+	var v1 bytes.Buffer
+
+	v1.Write(next(4))
+	require.Equal(t, version02, byteOrder.Uint32(next(4)))
+	_ = binary.Write(&v1, byteOrder, version01)
+
+	count := next(4)
+	v1.Write(count)
+
+	for range byteOrder.Uint32(count) {
+		tag := next(4)
+		require.Equal(t, privateKeyTag, byteOrder.Uint32(tag))
+		v1.Write(tag)
+		v1.Write(nextBytes(2)) // alias
+		v1.Write(next(8))      // creation time
+		v1.Write(nextBytes(4)) // encrypted key
+
+		chainLen := next(4)
+		v1.Write(chainLen)
+
+		for range byteOrder.Uint32(chainLen) {
+			nextBytes(2) // certificate type
+			v1.Write(nextBytes(4))
+		}
+	}
+
+	require.Equal(t, sha1.Size, r.Len())
+
+	h := sha1.New()
+	h.Write(passwordBytes([]byte("password"), false))
+	h.Write(whitenerMessage)
+	h.Write(v1.Bytes())
+	v1.Write(h.Sum(nil))
+
+	expected, err := os.ReadFile("./testdata/keystore_v1.jks")
+	require.NoError(t, err)
+	assert.Equal(t, expected, v1.Bytes())
+}
+
+func TestStoreVersion1(t *testing.T) {
+	expected := readKeyPasswordKeyStore(t)
+
+	expectedPKE, err := expected.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	require.NoError(t, err)
+
+	ks := New(WithOrderedAliases(), WithVersion1())
+	require.NoError(t, ks.SetPrivateKeyEntry("alias", expectedPKE, []byte("keypassword")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: expectedPKE.CreationTime,
+		Certificate:  expectedPKE.CertificateChain[0],
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("password")))
+	assert.Equal(t, []byte{0xfe, 0xed, 0xfe, 0xed, 0, 0, 0, 1}, buf.Bytes()[:8])
+
+	result, err := New().Verify(bytes.NewReader(buf.Bytes()), []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Version: 1, Entries: 2}, result)
+
+	loaded := New(WithOrderedAliases())
+	require.NoError(t, loaded.Load(bytes.NewReader(buf.Bytes()), []byte("password")))
+	assert.Equal(t, []string{"alias", "cert"}, loaded.Aliases())
+
+	pke, err := loaded.GetPrivateKeyEntry("alias", []byte("keypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, expectedPKE, pke)
+
+	tce, err := loaded.GetTrustedCertificateEntry("cert")
+	require.NoError(t, err)
+	assert.Equal(t, expectedPKE.CertificateChain[0], tce.Certificate)
+
+	require.NoError(t, ks.SetTrustedCertificateEntry("other", TrustedCertificateEntry{
+		CreationTime: expectedPKE.CreationTime,
+		Certificate:  Certificate{Type: "PGP", Content: []byte{1}},
+	}))
+
+	buf.Reset()
+
+	err = ks.Store(&buf, []byte("password"))
+	require.ErrorIs(t, err, ErrUnsupportedCertificateType)
+	assert.Zero(t, buf.Len())
+}
+
+func readKeyPasswordKeyStore(t *testing.T) KeyStore {
+	t.Helper()
+
+	content, err := os.ReadFile("./testdata/keystore_keypass.jks")
+	require.NoError(t, err)
+
+	ks := New()
+	require.NoError(t, ks.Load(bytes.NewReader(content), []byte("password")))
+
+	return ks
+}
+
 func TestLoadKeyPassword(t *testing.T) {
 	password := []byte{'p', 'a', 's', 's', 'w', 'o', 'r', 'd'}
 	defer zeroing(password)
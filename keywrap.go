@@ -0,0 +1,69 @@
+package keystore
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	keyWrapSemiblock = 8
+	keyWrapRounds    = 6
+	kwpICV           = 0xa65959a6
+	kwpICVLen        = 4
+)
+
+var errKeyWrapIntegrity = errors.New("key unwrap integrity check failed")
+
+// kwpUnwrap unwraps key wrapped with AES key wrap with padding of RFC 5649.
+func kwpUnwrap(block cipher.Block, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 2*keyWrapSemiblock || len(wrapped)%keyWrapSemiblock != 0 {
+		return nil, errors.New("got invalid wrapped key length")
+	}
+
+	n := len(wrapped)/keyWrapSemiblock - 1
+	out := make([]byte, len(wrapped))
+
+	if n == 1 {
+		block.Decrypt(out, wrapped)
+	} else {
+		copy(out, wrapped)
+
+		var (
+			b [2 * keyWrapSemiblock]byte
+			a = out[:keyWrapSemiblock]
+		)
+
+		for j := keyWrapRounds - 1; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				r := out[i*keyWrapSemiblock : (i+1)*keyWrapSemiblock]
+				t := binary.BigEndian.Uint64(a) ^ uint64(n*j+i) //nolint:gosec
+
+				binary.BigEndian.PutUint64(b[:keyWrapSemiblock], t)
+				copy(b[keyWrapSemiblock:], r)
+				block.Decrypt(b[:], b[:])
+				copy(a, b[:keyWrapSemiblock])
+				copy(r, b[keyWrapSemiblock:])
+			}
+		}
+
+		zeroing(b[:])
+	}
+
+	mli := int(binary.BigEndian.Uint32(out[kwpICVLen:keyWrapSemiblock]))
+	padLen := n*keyWrapSemiblock - mli
+
+	valid := binary.BigEndian.Uint32(out[:kwpICVLen]) == kwpICV && padLen >= 0 && padLen < keyWrapSemiblock
+	if valid {
+		valid = subtle.ConstantTimeCompare(out[keyWrapSemiblock+mli:], make([]byte, padLen)) == 1
+	}
+
+	if !valid {
+		zeroing(out)
+
+		return nil, errKeyWrapIntegrity
+	}
+
+	return out[keyWrapSemiblock : keyWrapSemiblock+mli], nil
+}
@@ -0,0 +1,188 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// CertificateIndex finds aliases by properties of entry certificates. TrustedCertificateEntry is indexed
+// by its certificate and PrivateKeyEntry by the leaf certificate of the chain, the same way
+// Java KeyStore.getCertificateAlias does. Certificates of types other than X.509 are not indexed.
+// The index is a snapshot, build a new one after the keystore is changed.
+type CertificateIndex struct {
+	entries      []indexedEntry
+	convertAlias func(string) string
+
+	byFingerprint map[[sha256.Size]byte][]int
+	bySubject     map[string][]int
+	byIssuer      map[string][]int
+	bySerial      map[string][]int
+	byDNSName     map[string][]int
+	bySKID        map[string][]int
+}
+
+type indexedEntry struct {
+	alias string
+	cert  *x509.Certificate
+}
+
+// Index parses certificates of the keystore entries and builds CertificateIndex.
+func (ks KeyStore) Index() (*CertificateIndex, error) {
+	idx := &CertificateIndex{
+		convertAlias:  ks.convertAlias,
+		byFingerprint: make(map[[sha256.Size]byte][]int),
+		bySubject:     make(map[string][]int),
+		byIssuer:      make(map[string][]int),
+		bySerial:      make(map[string][]int),
+		byDNSName:     make(map[string][]int),
+		bySKID:        make(map[string][]int),
+	}
+
+	for _, alias := range ks.Aliases() {
+		var certs []Certificate
+
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			certs = typedEntry.CertificateChain
+		case TrustedCertificateEntry:
+			certs = []Certificate{typedEntry.Certificate}
+		}
+
+		if len(certs) == 0 || !isX509CertificateType(certs[0].Type) {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(certs[0].Content)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate of %q: %w", alias, err)
+		}
+
+		idx.add(indexedEntry{alias: alias, cert: cert})
+	}
+
+	return idx, nil
+}
+
+func (idx *CertificateIndex) add(e indexedEntry) {
+	i := len(idx.entries)
+	idx.entries = append(idx.entries, e)
+
+	fingerprint := sha256.Sum256(e.cert.Raw)
+	idx.byFingerprint[fingerprint] = append(idx.byFingerprint[fingerprint], i)
+	idx.bySubject[e.cert.Subject.String()] = append(idx.bySubject[e.cert.Subject.String()], i)
+	idx.byIssuer[e.cert.Issuer.String()] = append(idx.byIssuer[e.cert.Issuer.String()], i)
+	idx.bySerial[e.cert.SerialNumber.String()] = append(idx.bySerial[e.cert.SerialNumber.String()], i)
+
+	for _, name := range e.cert.DNSNames {
+		name = strings.ToLower(name)
+		if n := len(idx.byDNSName[name]); n == 0 || idx.byDNSName[name][n-1] != i {
+			idx.byDNSName[name] = append(idx.byDNSName[name], i)
+		}
+	}
+
+	if len(e.cert.SubjectKeyId) > 0 {
+		idx.bySKID[string(e.cert.SubjectKeyId)] = append(idx.bySKID[string(e.cert.SubjectKeyId)], i)
+	}
+}
+
+// ByFingerprint returns aliases of entries which certificate has the SHA-256 fingerprint.
+func (idx *CertificateIndex) ByFingerprint(sha256Fingerprint []byte) []string {
+	var key [sha256.Size]byte
+	if len(sha256Fingerprint) != len(key) {
+		return nil
+	}
+
+	copy(key[:], sha256Fingerprint)
+
+	return idx.aliases(idx.byFingerprint[key])
+}
+
+// BySubject returns aliases of entries which certificate has the subject.
+// Subject is compared with the string form returned by pkix.Name.String.
+func (idx *CertificateIndex) BySubject(subject string) []string {
+	return idx.aliases(idx.bySubject[subject])
+}
+
+// ByIssuer returns aliases of entries which certificate has the issuer.
+// Issuer is compared with the string form returned by pkix.Name.String.
+func (idx *CertificateIndex) ByIssuer(issuer string) []string {
+	return idx.aliases(idx.byIssuer[issuer])
+}
+
+// BySerialNumber returns aliases of entries which certificate has the serial number.
+func (idx *CertificateIndex) BySerialNumber(serialNumber *big.Int) []string {
+	if serialNumber == nil {
+		return nil
+	}
+
+	return idx.aliases(idx.bySerial[serialNumber.String()])
+}
+
+// ByDNSName returns aliases of entries which certificate has the DNS name in subject alternative names.
+// Names are compared case-insensitively, wildcard names match only themselves.
+func (idx *CertificateIndex) ByDNSName(name string) []string {
+	return idx.aliases(idx.byDNSName[strings.ToLower(name)])
+}
+
+// BySubjectKeyID returns aliases of entries which certificate has the subject key identifier.
+func (idx *CertificateIndex) BySubjectKeyID(id []byte) []string {
+	return idx.aliases(idx.bySKID[string(id)])
+}
+
+// Certificate returns parsed certificate the entry is indexed by. Alias is converted by settings
+// of the keystore the index is built for.
+func (idx *CertificateIndex) Certificate(alias string) (*x509.Certificate, bool) {
+	alias = idx.convertAlias(alias)
+
+	for _, e := range idx.entries {
+		if e.alias == alias {
+			return e.cert, true
+		}
+	}
+
+	return nil, false
+}
+
+func (idx *CertificateIndex) aliases(positions []int) []string {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	aliases := make([]string, len(positions))
+	for i, p := range positions {
+		aliases[i] = idx.entries[p].alias
+	}
+
+	return aliases
+}
+
+// GetCertificateAlias returns alias of the first entry which certificate equals cert, the same way
+// Java KeyStore.getCertificateAlias does. Certificate of PrivateKeyEntry is the first one of the chain.
+func (ks KeyStore) GetCertificateAlias(cert []byte) (string, error) {
+	for _, alias := range ks.Aliases() {
+		var c Certificate
+
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			if len(typedEntry.CertificateChain) == 0 {
+				continue
+			}
+
+			c = typedEntry.CertificateChain[0]
+		case TrustedCertificateEntry:
+			c = typedEntry.Certificate
+		default:
+			continue
+		}
+
+		if bytes.Equal(c.Content, cert) {
+			return alias, nil
+		}
+	}
+
+	return "", ErrEntryNotFound
+}
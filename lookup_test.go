@@ -0,0 +1,113 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateIndex(t *testing.T) {
+	key := generateTestKey(t)
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          []byte{1, 2, 3},
+	}
+	ca := createTestCertificate(t, caTemplate, nil, &key.PublicKey, key)
+	server := createTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "server"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+		DNSNames:  []string{"Example.com", "*.example.com"},
+	}, ca, &key.PublicKey, key)
+
+	ks := New(WithOrderedAliases())
+	require.NoError(t, ks.SetTrustedCertificateEntry("ca", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: X509CertificateType, Content: ca.Raw},
+	}))
+	require.NoError(t, ks.SetPrivateKeyEntry("server", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+		CertificateChain: []Certificate{
+			{Type: X509CertificateType, Content: server.Raw},
+			{Type: X509CertificateType, Content: ca.Raw},
+		},
+	}, []byte("password")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("other", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "other", Content: []byte{1}},
+	}))
+
+	idx, err := ks.Index()
+	require.NoError(t, err)
+
+	fingerprint := sha256.Sum256(server.Raw)
+	assert.Equal(t, []string{"server"}, idx.ByFingerprint(fingerprint[:]))
+	assert.Nil(t, idx.ByFingerprint([]byte{1}))
+	assert.Equal(t, []string{"ca"}, idx.BySubject("CN=ca"))
+	assert.Equal(t, []string{"ca", "server"}, idx.ByIssuer("CN=ca"))
+	assert.Equal(t, []string{"server"}, idx.BySerialNumber(server.SerialNumber))
+	assert.Nil(t, idx.BySerialNumber(big.NewInt(0)))
+	assert.Equal(t, []string{"server"}, idx.ByDNSName("example.COM"))
+	assert.Equal(t, []string{"server"}, idx.ByDNSName("*.example.com"))
+	assert.Nil(t, idx.ByDNSName("www.example.com"))
+	assert.Equal(t, []string{"ca"}, idx.BySubjectKeyID([]byte{1, 2, 3}))
+
+	cert, ok := idx.Certificate("server")
+	require.True(t, ok)
+	assert.Equal(t, server.Raw, cert.Raw)
+
+	cert, ok = idx.Certificate("Server")
+	require.True(t, ok)
+	assert.Equal(t, server.Raw, cert.Raw)
+
+	_, ok = idx.Certificate("other")
+	assert.False(t, ok)
+
+	caseExact := New(WithCaseExactAliases())
+	require.NoError(t, caseExact.SetTrustedCertificateEntry("CA", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: X509CertificateType, Content: ca.Raw},
+	}))
+
+	idx, err = caseExact.Index()
+	require.NoError(t, err)
+
+	_, ok = idx.Certificate("CA")
+	assert.True(t, ok)
+
+	_, ok = idx.Certificate("ca")
+	assert.False(t, ok)
+
+	alias, err := ks.GetCertificateAlias(ca.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, "ca", alias)
+
+	alias, err = ks.GetCertificateAlias(server.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, "server", alias)
+
+	_, err = ks.GetCertificateAlias([]byte{1, 2, 3})
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestCertificateIndexInvalidCertificate(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetTrustedCertificateEntry("broken", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: X509CertificateType, Content: []byte{1, 2, 3}},
+	}))
+
+	_, err := ks.Index()
+	require.Error(t, err)
+}
@@ -0,0 +1,349 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a declarative description of a keystore. It is usually kept in YAML or JSON file.
+//
+//	storePassword:
+//	  env: STORE_PASSWORD
+//	entries:
+//	  - alias: server
+//	    type: PrivateKeyEntry
+//	    privateKey: server.key
+//	    certificates: [server.pem, ca.pem]
+//	    keyPassword:
+//	      file: /run/secrets/key-password
+//	  - alias: ca
+//	    type: TrustedCertificateEntry
+//	    certificates: [ca.pem]
+type Manifest struct {
+	StorePassword    PasswordRef     `json:"storePassword"              yaml:"storePassword"`
+	OrderedAliases   bool            `json:"orderedAliases,omitempty"   yaml:"orderedAliases,omitempty"`
+	CaseExactAliases bool            `json:"caseExactAliases,omitempty" yaml:"caseExactAliases,omitempty"`
+	MinPasswordLen   int             `json:"minPasswordLen,omitempty"   yaml:"minPasswordLen,omitempty"`
+	Entries          []ManifestEntry `json:"entries"                    yaml:"entries"`
+
+	// BaseDir is a directory relative source paths are resolved against.
+	BaseDir string `json:"-" yaml:"-"`
+}
+
+// ManifestEntry describes a single keystore entry. Sources are PEM or DER encoded files.
+type ManifestEntry struct {
+	Alias string `json:"alias" yaml:"alias"`
+	// Type is PrivateKeyEntry or TrustedCertificateEntry.
+	Type string `json:"type" yaml:"type"`
+	// CreationTime defaults to the build time.
+	CreationTime time.Time `json:"creationTime,omitempty" yaml:"creationTime,omitempty"`
+	// PrivateKey is a PKCS#8, PKCS#1 or SEC 1 private key file of PrivateKeyEntry.
+	PrivateKey string `json:"privateKey,omitempty" yaml:"privateKey,omitempty"`
+	// Certificates are files with the certificate chain of PrivateKeyEntry from the leaf to the root
+	// or a single file with the certificate of TrustedCertificateEntry.
+	Certificates []string `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	// KeyPassword of PrivateKeyEntry defaults to the store password.
+	KeyPassword *PasswordRef `json:"keyPassword,omitempty" yaml:"keyPassword,omitempty"`
+}
+
+// PasswordRef points to a password kept in the environment variable or in the file.
+// Trailing line break is removed from the file content.
+type PasswordRef struct {
+	Env  string `json:"env,omitempty"  yaml:"env,omitempty"`
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+// ManifestError reports manifest entry that failed validation or build.
+type ManifestError struct {
+	Index int
+	Alias string
+	Err   error
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("manifest entry %d (alias %q): %v", e.Index, e.Alias, e.Err)
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
+
+// ParseManifest parses YAML or JSON manifest.
+func ParseManifest(data []byte) (Manifest, error) {
+	var m Manifest
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return Manifest{}, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// LoadManifest reads manifest from the file. Relative source paths are resolved against its directory.
+func LoadManifest(filename string) (Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+
+	m, err := ParseManifest(data)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	m.BaseDir = filepath.Dir(filename)
+
+	return m, nil
+}
+
+// Validate checks the manifest structure without reading sources and passwords.
+func (m Manifest) Validate() error {
+	if err := m.StorePassword.validate(); err != nil {
+		return fmt.Errorf("validate store password: %w", err)
+	}
+
+	ks := m.newKeyStore()
+	aliases := make(map[string]int, len(m.Entries))
+
+	for i, entry := range m.Entries {
+		if err := entry.validate(); err != nil {
+			return &ManifestError{Index: i, Alias: entry.Alias, Err: err}
+		}
+
+		alias := ks.convertAlias(entry.Alias)
+		if j, ok := aliases[alias]; ok {
+			return &ManifestError{Index: i, Alias: entry.Alias, Err: fmt.Errorf("alias is used by entry %d", j)}
+		}
+
+		aliases[alias] = i
+	}
+
+	return nil
+}
+
+// Build validates the manifest, reads all sources and passwords and writes the keystore into w.
+func (m Manifest) Build(w io.Writer) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	ks := m.newKeyStore()
+
+	for i, entry := range m.Entries {
+		if err := m.addEntry(ks, entry); err != nil {
+			return &ManifestError{Index: i, Alias: entry.Alias, Err: err}
+		}
+	}
+
+	if err := ks.StoreWithSource(w, m.StorePassword.source(m.BaseDir)); err != nil {
+		return fmt.Errorf("store keystore: %w", err)
+	}
+
+	return nil
+}
+
+func (m Manifest) newKeyStore() KeyStore {
+	var options []Option
+
+	if m.OrderedAliases {
+		options = append(options, WithOrderedAliases())
+	}
+
+	if m.CaseExactAliases {
+		options = append(options, WithCaseExactAliases())
+	}
+
+	if m.MinPasswordLen > 0 {
+		options = append(options, WithMinPasswordLen(m.MinPasswordLen))
+	}
+
+	return New(options...)
+}
+
+func (m Manifest) addEntry(ks KeyStore, entry ManifestEntry) error {
+	creationTime := entry.CreationTime
+	if creationTime.IsZero() {
+		creationTime = time.Now()
+	}
+
+	var chain []Certificate
+
+	for _, source := range entry.Certificates {
+		certs, err := readCertificateFile(m.path(source))
+		if err != nil {
+			return fmt.Errorf("read certificates %s: %w", source, err)
+		}
+
+		chain = append(chain, certs...)
+	}
+
+	if entry.Type == trustedCertificateEntryType {
+		if len(chain) != 1 {
+			return fmt.Errorf("got %d certificates, expected exactly one", len(chain))
+		}
+
+		tce := TrustedCertificateEntry{
+			CreationTime: creationTime,
+			Certificate:  chain[0],
+		}
+
+		return ks.SetTrustedCertificateEntry(entry.Alias, tce)
+	}
+
+	key, err := readPrivateKeyFile(m.path(entry.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("read private key %s: %w", entry.PrivateKey, err)
+	}
+
+	defer zeroing(key)
+
+	passwordRef := m.StorePassword
+	if entry.KeyPassword != nil {
+		passwordRef = *entry.KeyPassword
+	}
+
+	pke := PrivateKeyEntry{
+		CreationTime:     creationTime,
+		PrivateKey:       key,
+		CertificateChain: chain,
+	}
+
+	return ks.SetPrivateKeyEntryWithSource(entry.Alias, pke, passwordRef.source(m.BaseDir))
+}
+
+func (m Manifest) path(source string) string {
+	if filepath.IsAbs(source) || m.BaseDir == "" {
+		return source
+	}
+
+	return filepath.Join(m.BaseDir, source)
+}
+
+func (e ManifestEntry) validate() error {
+	if e.Alias == "" {
+		return errors.New("empty alias")
+	}
+
+	switch e.Type {
+	case privateKeyEntryType:
+		if e.PrivateKey == "" {
+			return errors.New("private key is required")
+		}
+
+		if e.KeyPassword != nil {
+			if err := e.KeyPassword.validate(); err != nil {
+				return fmt.Errorf("validate key password: %w", err)
+			}
+		}
+	case trustedCertificateEntryType:
+		if e.PrivateKey != "" || e.KeyPassword != nil {
+			return errors.New("trusted certificate entry can not have private key")
+		}
+
+		if len(e.Certificates) != 1 {
+			return errors.New("trusted certificate entry must have exactly one certificate source")
+		}
+	default:
+		return fmt.Errorf("got unknown entry type %q", e.Type)
+	}
+
+	return nil
+}
+
+func (p PasswordRef) validate() error {
+	if (p.Env == "") == (p.File == "") {
+		return errors.New("exactly one of env or file must be set")
+	}
+
+	return nil
+}
+
+func (p PasswordRef) source(baseDir string) PasswordSource {
+	if p.Env != "" {
+		return EnvPassword(p.Env)
+	}
+
+	filename := p.File
+	if !filepath.IsAbs(filename) && baseDir != "" {
+		filename = filepath.Join(baseDir, filename)
+	}
+
+	return FilePassword(filename)
+}
+
+// readCertificateFile reads all PEM encoded certificates or a single DER encoded certificate from the file.
+func readCertificateFile(filename string) ([]Certificate, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []Certificate
+
+	rest := content
+
+	for {
+		var b *pem.Block
+
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+
+		if b.Type == "CERTIFICATE" {
+			certs = append(certs, Certificate{Type: X509CertificateType, Content: b.Bytes})
+		}
+	}
+
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	if _, err := x509.ParseCertificate(content); err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	return []Certificate{{Type: X509CertificateType, Content: content}}, nil
+}
+
+// readPrivateKeyFile reads PEM or DER encoded private key from the file and returns it PKCS#8 encoded.
+func readPrivateKeyFile(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	der := content
+	if b, _ := pem.Decode(content); b != nil {
+		der = b.Bytes
+		defer zeroing(content)
+	}
+
+	if _, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return der, nil
+	}
+
+	defer zeroing(der)
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return x509.MarshalPKCS8PrivateKey(key)
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return x509.MarshalPKCS8PrivateKey(key)
+	}
+
+	return nil, errors.New("got unsupported private key format")
+}
@@ -0,0 +1,171 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestBuild(t *testing.T) {
+	dir := t.TempDir()
+
+	copyTestFile(t, "./testdata/key.pem", filepath.Join(dir, "key.pem"))
+	copyTestFile(t, "./testdata/cert.pem", filepath.Join(dir, "cert.pem"))
+
+	err := os.WriteFile(filepath.Join(dir, "key-password"), []byte("keypassword\n"), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("TEST_STORE_PASSWORD", "password")
+
+	manifest := []byte(`
+storePassword:
+  env: TEST_STORE_PASSWORD
+orderedAliases: true
+entries:
+  - alias: Server
+    type: PrivateKeyEntry
+    privateKey: key.pem
+    certificates: [cert.pem]
+    keyPassword:
+      file: key-password
+  - alias: ca
+    type: TrustedCertificateEntry
+    creationTime: 2020-10-29T19:25:12Z
+    certificates: [cert.pem]
+`)
+
+	err = os.WriteFile(filepath.Join(dir, "keystore.yaml"), manifest, 0o600)
+	require.NoError(t, err)
+
+	m, err := LoadManifest(filepath.Join(dir, "keystore.yaml"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, m.Build(&buf))
+
+	ks := New()
+	require.NoError(t, ks.Load(&buf, []byte("password")))
+	assert.Equal(t, []string{"ca", "server"}, sortedAliases(ks))
+
+	pke, err := ks.GetPrivateKeyEntry("server", []byte("keypassword"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+	assert.Equal(t, []Certificate{{Type: X509CertificateType, Content: readCertificate(t)}}, pke.CertificateChain)
+
+	tce, err := ks.GetTrustedCertificateEntry("ca")
+	require.NoError(t, err)
+	assert.Equal(t, readCertificate(t), tce.Certificate.Content)
+	assert.Equal(t, int64(1603999512), tce.CreationTime.Unix())
+}
+
+func TestManifestJSON(t *testing.T) {
+	m, err := ParseManifest([]byte(`{
+		"storePassword": {"env": "TEST_STORE_PASSWORD"},
+		"entries": [{"alias": "ca", "type": "TrustedCertificateEntry", "certificates": ["testdata/cert.pem"]}]
+	}`))
+	require.NoError(t, err)
+
+	t.Setenv("TEST_STORE_PASSWORD", "password")
+
+	var buf bytes.Buffer
+
+	require.NoError(t, m.Build(&buf))
+
+	ks := New()
+	require.NoError(t, ks.Load(&buf, []byte("password")))
+	assert.True(t, ks.IsTrustedCertificateEntry("ca"))
+}
+
+func TestManifestErrors(t *testing.T) {
+	table := []struct {
+		name     string
+		manifest string
+		index    int
+		alias    string
+	}{
+		{
+			name: "unknown type",
+			manifest: `{"storePassword": {"env": "TEST_STORE_PASSWORD"}, "entries": [
+				{"alias": "a", "type": "TrustedCertificateEntry", "certificates": ["testdata/cert.pem"]},
+				{"alias": "b", "type": "SecretKeyEntry"}]}`,
+			index: 1,
+			alias: "b",
+		},
+		{
+			name: "duplicate alias",
+			manifest: `{"storePassword": {"env": "TEST_STORE_PASSWORD"}, "entries": [
+				{"alias": "a", "type": "TrustedCertificateEntry", "certificates": ["testdata/cert.pem"]},
+				{"alias": "A", "type": "TrustedCertificateEntry", "certificates": ["testdata/cert.pem"]}]}`,
+			index: 1,
+			alias: "A",
+		},
+		{
+			name: "missing source",
+			manifest: `{"storePassword": {"env": "TEST_STORE_PASSWORD"}, "entries": [
+				{"alias": "a", "type": "TrustedCertificateEntry", "certificates": ["testdata/cert.pem"]},
+				{"alias": "b", "type": "PrivateKeyEntry", "privateKey": "testdata/missing.pem"}]}`,
+			index: 1,
+			alias: "b",
+		},
+		{
+			name: "bad password reference",
+			manifest: `{"storePassword": {"env": "TEST_STORE_PASSWORD"}, "entries": [
+				{"alias": "a", "type": "PrivateKeyEntry", "privateKey": "testdata/key.pem",
+				 "keyPassword": {"env": "A", "file": "b"}}]}`,
+			index: 0,
+			alias: "a",
+		},
+		{
+			name: "unset password variable",
+			manifest: `{"storePassword": {"env": "TEST_STORE_PASSWORD"}, "entries": [
+				{"alias": "a", "type": "PrivateKeyEntry", "privateKey": "testdata/key.pem",
+				 "keyPassword": {"env": "TEST_UNSET_PASSWORD"}}]}`,
+			index: 0,
+			alias: "a",
+		},
+	}
+
+	t.Setenv("TEST_STORE_PASSWORD", "password")
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseManifest([]byte(tt.manifest))
+			require.NoError(t, err)
+
+			err = m.Build(&bytes.Buffer{})
+
+			var manifestErr *ManifestError
+
+			require.ErrorAs(t, err, &manifestErr)
+			assert.Equal(t, tt.index, manifestErr.Index)
+			assert.Equal(t, tt.alias, manifestErr.Alias)
+		})
+	}
+
+	_, err := ParseManifest([]byte(`{"unknown": true}`))
+	require.Error(t, err)
+
+	m, err := ParseManifest([]byte(`{"entries": []}`))
+	require.NoError(t, err)
+	require.Error(t, m.Validate(), "store password must be required")
+}
+
+func copyTestFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	content, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(dst, content, 0o600))
+}
+
+func sortedAliases(ks KeyStore) []string {
+	ks.ordered = true
+
+	return ks.Aliases()
+}
@@ -0,0 +1,62 @@
+package keystore
+
+import (
+	"errors"
+	"sync"
+)
+
+// errMemoryLockLimit is returned by lockMemory if locking would exceed the limit of locked memory.
+var errMemoryLockLimit = errors.New("memory lock limit exceeded")
+
+// lockedBuffers are buffers locked in memory by their first byte. They are not moved by the garbage
+// collector and stay mapped until destroySecret is called.
+var lockedBuffers = struct {
+	sync.Mutex
+	m map[*byte][]byte
+}{m: make(map[*byte][]byte)}
+
+// newSecretBuffer returns buffer of n bytes for a secret. The buffer is locked in memory, so it is not
+// swapped out, if locked is set and the platform supports it. The buffer is allocated on the heap if the limit
+// of locked memory is reached. It must be released with destroySecret exactly once.
+func newSecretBuffer(n int, locked bool) ([]byte, error) {
+	if !locked || n == 0 {
+		return make([]byte, n), nil
+	}
+
+	buf, err := lockMemory(n)
+	if errors.Is(err, errors.ErrUnsupported) || errors.Is(err, errMemoryLockLimit) {
+		return make([]byte, n), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	lockedBuffers.Lock()
+	lockedBuffers.m[&buf[0]] = buf
+	lockedBuffers.Unlock()
+
+	return buf, nil
+}
+
+// destroySecret fills buf with zeros and unlocks it if it was returned by newSecretBuffer.
+// Locked buffer is unmapped, so it must not be used afterwards.
+func destroySecret(buf []byte) {
+	if len(buf) == 0 {
+		return
+	}
+
+	lockedBuffers.Lock()
+	locked, ok := lockedBuffers.m[&buf[0]]
+	delete(lockedBuffers.m, &buf[0])
+	lockedBuffers.Unlock()
+
+	if !ok {
+		zeroing(buf)
+
+		return
+	}
+
+	zeroing(locked)
+	unlockMemory(locked)
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+func lockMemory(n int) ([]byte, error) {
+	buf, err := syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("map memory: %w", err)
+	}
+
+	if err := syscall.Mlock(buf); err != nil {
+		_ = syscall.Munmap(buf)
+
+		// EPERM is returned instead of ENOMEM if the limit is zero.
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.ENOMEM) || errors.Is(err, syscall.EPERM) {
+			return nil, fmt.Errorf("lock memory: %w: %w", errMemoryLockLimit, err)
+		}
+
+		return nil, fmt.Errorf("lock memory: %w", err)
+	}
+
+	return buf, nil
+}
+
+func unlockMemory(buf []byte) {
+	_ = syscall.Munlock(buf)
+	_ = syscall.Munmap(buf)
+}
@@ -0,0 +1,48 @@
+//go:build linux
+
+package keystore
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rlimitMemlock is RLIMIT_MEMLOCK which is not defined by syscall package.
+const rlimitMemlock = 8
+
+func TestSecretBufferLockLimit(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("limit of locked memory does not apply to root")
+	}
+
+	var limit syscall.Rlimit
+	require.NoError(t, syscall.Getrlimit(rlimitMemlock, &limit))
+
+	defer func() { require.NoError(t, syscall.Setrlimit(rlimitMemlock, &limit)) }()
+
+	for _, cur := range []uint64{0, 16} {
+		require.NoError(t, syscall.Setrlimit(rlimitMemlock, &syscall.Rlimit{Cur: cur, Max: limit.Max}))
+		checkSecretBufferOnHeap(t)
+	}
+}
+
+func checkSecretBufferOnHeap(t *testing.T) {
+	t.Helper()
+
+	buf, err := newSecretBuffer(32, true)
+	require.NoError(t, err)
+	require.Len(t, buf, 32)
+
+	lockedBuffers.Lock()
+	_, locked := lockedBuffers.m[&buf[0]]
+	lockedBuffers.Unlock()
+	assert.False(t, locked)
+
+	copy(buf, "secret")
+	destroySecret(buf)
+	assert.Equal(t, make([]byte, 32), buf)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package keystore
+
+import (
+	"errors"
+)
+
+func lockMemory(int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func unlockMemory([]byte) {}
@@ -0,0 +1,88 @@
+package keystore
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretBuffer(t *testing.T) {
+	buf, err := newSecretBuffer(32, true)
+	require.NoError(t, err)
+	require.Len(t, buf, 32)
+
+	copy(buf, "secret")
+
+	lockedBuffers.Lock()
+	_, locked := lockedBuffers.m[&buf[0]]
+	lockedBuffers.Unlock()
+	assert.Equal(t, runtime.GOOS == "linux", locked)
+
+	ptr := &buf[0]
+	destroySecret(buf)
+
+	lockedBuffers.Lock()
+	_, locked = lockedBuffers.m[ptr]
+	lockedBuffers.Unlock()
+	assert.False(t, locked)
+
+	buf, err = newSecretBuffer(4, false)
+	require.NoError(t, err)
+	copy(buf, "test")
+
+	destroySecret(buf)
+	assert.Equal(t, make([]byte, 4), buf)
+}
+
+func TestWithLockedMemory(t *testing.T) {
+	ks := New(WithLockedMemory())
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: X509CertificateType, Content: readCertificate(t)}},
+	}, []byte("password")))
+
+	pke, err := ks.GetPrivateKeyEntry("key", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+
+	lockedBuffers.Lock()
+	assert.Empty(t, lockedBuffers.m)
+	lockedBuffers.Unlock()
+
+	key := pke.PrivateKey
+	pke.Destroy()
+	pke.Destroy()
+	assert.Equal(t, make([]byte, len(key)), key)
+
+	result := ks.UnlockPrivateKeyEntries(UnlockOptions{Passwords: map[string][]byte{"key": []byte("password")}})
+	require.Contains(t, result.Entries, "key")
+	result.Entries["key"].Destroy()
+	result.Zeroing()
+
+	_, err = ks.GetPrivateKeyEntry("key", []byte("wrong"))
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	lockedBuffers.Lock()
+	assert.Empty(t, lockedBuffers.m)
+	lockedBuffers.Unlock()
+}
+
+func TestEncryptWithLockedMemory(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, saltLen)
+
+	expected, err := keyProtector{}.encrypt(bytes.NewReader(salt), readPrivateKey(t), []byte("password"))
+	require.NoError(t, err)
+
+	actual, err := keyProtector{lockedMemory: true}.encrypt(bytes.NewReader(salt), readPrivateKey(t), []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	lockedBuffers.Lock()
+	assert.Empty(t, lockedBuffers.m)
+	lockedBuffers.Unlock()
+}
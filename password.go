@@ -0,0 +1,185 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PasswordSource provides a password on demand. The keystore asks for the password only when it is
+// needed and fills the returned slice with zeros after usage, so every call must return a new slice.
+type PasswordSource interface {
+	Password() ([]byte, error)
+}
+
+// PasswordFunc is a callback PasswordSource.
+type PasswordFunc func() ([]byte, error)
+
+// Password calls f.
+func (f PasswordFunc) Password() ([]byte, error) {
+	return f()
+}
+
+// KeyPasswordResolver returns source of the key password for the alias.
+type KeyPasswordResolver func(alias string) PasswordSource
+
+// KeyPasswords returns KeyPasswordResolver which looks up sources by alias and falls back to fallback.
+// Aliases are matched exactly, fallback may be nil.
+func KeyPasswords(sources map[string]PasswordSource, fallback PasswordSource) KeyPasswordResolver {
+	return func(alias string) PasswordSource {
+		if source, ok := sources[alias]; ok {
+			return source
+		}
+
+		return fallback
+	}
+}
+
+// EnvPassword returns PasswordSource reading password from the environment variable.
+func EnvPassword(name string) PasswordSource {
+	return PasswordFunc(func() ([]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return []byte(value), nil
+	})
+}
+
+// FilePassword returns PasswordSource reading password from the file. Trailing line break is removed.
+func FilePassword(filename string) PasswordSource {
+	return PasswordFunc(func() ([]byte, error) {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("read password file: %w", err)
+		}
+
+		return trimLineBreak(content), nil
+	})
+}
+
+// PromptPassword returns PasswordSource writing prompt to the standard error and reading password
+// from the standard input without echo. A line is read as is if the standard input is not a terminal.
+func PromptPassword(prompt string) PasswordSource {
+	return promptPassword(prompt, os.Stdin, os.Stderr)
+}
+
+func promptPassword(prompt string, in *os.File, out io.Writer) PasswordSource {
+	return PasswordFunc(func() ([]byte, error) {
+		if _, err := io.WriteString(out, prompt); err != nil {
+			return nil, fmt.Errorf("write prompt: %w", err)
+		}
+
+		if fd := int(in.Fd()); term.IsTerminal(fd) {
+			password, err := term.ReadPassword(fd)
+
+			_, _ = io.WriteString(out, "\n")
+
+			if err != nil {
+				return nil, fmt.Errorf("read password: %w", err)
+			}
+
+			return password, nil
+		}
+
+		return readLine(in)
+	})
+}
+
+// readLine reads bytes up to a line break without buffering beyond it.
+func readLine(r io.Reader) ([]byte, error) {
+	var (
+		line []byte
+		b    [1]byte
+	)
+
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			if b[0] == '\n' {
+				return trimLineBreak(line), nil
+			}
+
+			line = append(line, b[0])
+		}
+
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			return line, nil
+		}
+
+		if err != nil {
+			zeroing(line)
+
+			return nil, fmt.Errorf("read password: %w", err)
+		}
+	}
+}
+
+func trimLineBreak(content []byte) []byte {
+	password := bytes.TrimRight(content, "\r\n")
+	zeroing(content[len(password):])
+
+	return password
+}
+
+// LoadWithSource reads keystore representation from r and checks its signature using password from source.
+func (ks KeyStore) LoadWithSource(r io.Reader, source PasswordSource) error {
+	password, err := source.Password()
+	if err != nil {
+		return fmt.Errorf("get password: %w", err)
+	}
+
+	defer zeroing(password)
+
+	return ks.Load(r, password)
+}
+
+// StoreWithSource signs keystore using password from source and writes its representation into w.
+func (ks KeyStore) StoreWithSource(w io.Writer, source PasswordSource) error {
+	password, err := source.Password()
+	if err != nil {
+		return fmt.Errorf("get password: %w", err)
+	}
+
+	defer zeroing(password)
+
+	return ks.Store(w, password)
+}
+
+// SetPrivateKeyEntryWithSource adds PrivateKeyEntry into keystore by alias encrypted with password from source.
+func (ks KeyStore) SetPrivateKeyEntryWithSource(alias string, entry PrivateKeyEntry, source PasswordSource) error {
+	if err := ks.validatePrivateKeyEntry(entry); err != nil {
+		return fmt.Errorf("validate private key entry: %w", err)
+	}
+
+	password, err := source.Password()
+	if err != nil {
+		return fmt.Errorf("get password: %w", err)
+	}
+
+	defer zeroing(password)
+
+	return ks.SetPrivateKeyEntry(alias, entry, password)
+}
+
+// GetPrivateKeyEntryWithSource returns PrivateKeyEntry from the keystore by the alias decrypted with
+// password from source.
+func (ks KeyStore) GetPrivateKeyEntryWithSource(alias string, source PasswordSource) (PrivateKeyEntry, error) {
+	if !ks.IsPrivateKeyEntry(alias) {
+		return ks.GetPrivateKeyEntry(alias, nil)
+	}
+
+	password, err := source.Password()
+	if err != nil {
+		return PrivateKeyEntry{}, fmt.Errorf("get password: %w", err)
+	}
+
+	defer zeroing(password)
+
+	return ks.GetPrivateKeyEntry(alias, password)
+}
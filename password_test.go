@@ -0,0 +1,134 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordSources(t *testing.T) {
+	t.Setenv("TEST_PASSWORD", "password")
+
+	password, err := EnvPassword("TEST_PASSWORD").Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("password"), password)
+
+	_, err = EnvPassword("TEST_UNSET_PASSWORD").Password()
+	require.Error(t, err)
+
+	filename := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(filename, []byte("password\r\n"), 0o600))
+
+	password, err = FilePassword(filename).Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("password"), password)
+
+	_, err = FilePassword(filepath.Join(t.TempDir(), "missing")).Password()
+	require.Error(t, err)
+}
+
+func TestPromptPasswordWithoutTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	_, err = w.WriteString("password\nrest\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var prompt bytes.Buffer
+
+	source := promptPassword("Password: ", r, &prompt)
+
+	password, err := source.Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("password"), password)
+	assert.Equal(t, "Password: ", prompt.String())
+
+	password, err = source.Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("rest"), password)
+
+	_, err = source.Password()
+	require.Error(t, err)
+}
+
+func TestWithSourceZeroesPasswords(t *testing.T) {
+	var issued [][]byte
+
+	source := PasswordFunc(func() ([]byte, error) {
+		password := []byte("password")
+		issued = append(issued, password)
+
+		return password, nil
+	})
+
+	ks := New()
+	pke := PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}
+
+	require.NoError(t, ks.SetPrivateKeyEntryWithSource("alias", pke, source))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ks.StoreWithSource(&buf, source))
+
+	loaded := New()
+	require.NoError(t, loaded.LoadWithSource(&buf, source))
+
+	actual, err := loaded.GetPrivateKeyEntryWithSource("alias", source)
+	require.NoError(t, err)
+	assert.Equal(t, pke.PrivateKey, actual.PrivateKey)
+
+	require.Len(t, issued, 4)
+
+	for _, password := range issued {
+		assert.Equal(t, make([]byte, len(password)), password)
+	}
+}
+
+func TestWithSourceFetchesPasswordLazily(t *testing.T) {
+	errSource := errors.New("source failed")
+	source := PasswordFunc(func() ([]byte, error) {
+		return nil, errSource
+	})
+
+	ks := New()
+
+	_, err := ks.GetPrivateKeyEntryWithSource("missing", source)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	err = ks.SetPrivateKeyEntryWithSource("alias", PrivateKeyEntry{}, source)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errSource)
+
+	err = ks.StoreWithSource(&bytes.Buffer{}, source)
+	require.ErrorIs(t, err, errSource)
+}
+
+func TestKeyPasswords(t *testing.T) {
+	server := PasswordFunc(func() ([]byte, error) { return []byte("server"), nil })
+	fallback := PasswordFunc(func() ([]byte, error) { return []byte("fallback"), nil })
+
+	resolver := KeyPasswords(map[string]PasswordSource{"server": server}, fallback)
+
+	password, err := resolver("server").Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("server"), password)
+
+	password, err = resolver("client").Password()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("fallback"), password)
+
+	assert.Nil(t, KeyPasswords(nil, nil)("client"))
+}
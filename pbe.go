@@ -0,0 +1,439 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	defaultPBEIterations = 10000
+	defaultPBESaltLen    = 20
+	// maxPBEIterations limits iteration counts read from files the way Java does,
+	// so crafted files can not force almost unlimited key derivation work.
+	maxPBEIterations = 5000000
+)
+
+// PKCS#12 key derivation purposes, RFC 7292 appendix B.3.
+const (
+	pkcs12KeyID  = 1
+	pkcs12IVID   = 2
+	pkcs12MACID  = 3
+	bmpCharLen   = 2
+	aes256KeyLen = 32
+)
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidSHA3512         = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 10}
+	oidHMACWithSHA3512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 16}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd2KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+)
+
+var digests = []struct {
+	oid     asn1.ObjectIdentifier
+	hmacOID asn1.ObjectIdentifier
+	new     func() hash.Hash
+}{
+	{oidSHA1, oidHMACWithSHA1, sha1.New},
+	{oidSHA256, oidHMACWithSHA256, sha256.New},
+	{oidSHA384, oidHMACWithSHA384, sha512.New384},
+	{oidSHA512, oidHMACWithSHA512, sha512.New},
+	{oidSHA3512, oidHMACWithSHA3512, sha3.New512},
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+func digestByOID(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	for _, d := range digests {
+		if d.oid.Equal(oid) {
+			return d.new, nil
+		}
+	}
+
+	return nil, fmt.Errorf("got unsupported digest algorithm %s", oid)
+}
+
+func digestByHMACOID(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	if len(oid) == 0 {
+		return sha1.New, nil
+	}
+
+	for _, d := range digests {
+		if d.hmacOID.Equal(oid) {
+			return d.new, nil
+		}
+	}
+
+	return nil, fmt.Errorf("got unsupported pseudorandom function %s", oid)
+}
+
+func checkIterations(iterations int) error {
+	if iterations < 1 || iterations > maxPBEIterations {
+		return fmt.Errorf("got invalid iteration count %d", iterations)
+	}
+
+	return nil
+}
+
+// bmpPassword converts UTF-8 password to a null terminated BMPString as PKCS#12 key derivation expects.
+func bmpPassword(password []byte) []byte {
+	if len(password) == 0 {
+		return make([]byte, bmpCharLen)
+	}
+
+	bmp := make([]byte, 0, (len(password)+1)*bmpCharLen)
+
+	for rest := password; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+
+		if r >= 0x10000 {
+			r = utf8.RuneError
+		}
+
+		bmp = append(bmp, byte(r>>8), byte(r)) //nolint:gomnd,mnd
+	}
+
+	return append(bmp, 0, 0)
+}
+
+// pkcs12KDF derives key material from BMPString password, RFC 7292 appendix B.2.
+func pkcs12KDF(newHash func() hash.Hash, password, salt []byte, iterations, id, size int) []byte {
+	h := newHash()
+	u := h.Size()
+	v := h.BlockSize()
+
+	d := bytes.Repeat([]byte{byte(id)}, v)
+	i := append(fillBlocks(salt, v), fillBlocks(password, v)...)
+
+	defer zeroing(i)
+
+	var (
+		out []byte
+		one = big.NewInt(1)
+	)
+
+	for len(out) < size {
+		h.Reset()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+
+		for j := 1; j < iterations; j++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+
+		out = append(out, a...)
+
+		if len(out) >= size {
+			break
+		}
+
+		b := new(big.Int).SetBytes(fillBlocks(a[:u], v)[:v])
+		b.Add(b, one)
+
+		for k := 0; k < len(i); k += v {
+			block := new(big.Int).SetBytes(i[k : k+v])
+			block.Add(block, b)
+
+			sum := block.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+
+			copy(i[k:k+v], make([]byte, v-len(sum)))
+			copy(i[k+v-len(sum):k+v], sum)
+		}
+	}
+
+	return out[:size]
+}
+
+// fillBlocks repeats data to fill whole number of v bytes blocks.
+func fillBlocks(data []byte, v int) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	n := v * ((len(data) + v - 1) / v)
+	out := make([]byte, n)
+
+	for i := 0; i < n; i += len(data) {
+		copy(out[i:], data)
+	}
+
+	return out
+}
+
+// pbeDecrypt decrypts data encrypted with PBES2 or PKCS#12 password based encryption scheme.
+func pbeDecrypt(algorithm pkix.AlgorithmIdentifier, data, password []byte) ([]byte, error) {
+	block, iv, err := pbeCipher(algorithm, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("got invalid encrypted data length")
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	unpadded, err := unpad(plain, block.BlockSize())
+	if err != nil {
+		zeroing(plain)
+
+		return nil, fmt.Errorf("decrypt: %w", ErrIncorrectPassword)
+	}
+
+	return unpadded, nil
+}
+
+func pbeCipher(algorithm pkix.AlgorithmIdentifier, password []byte) (cipher.Block, []byte, error) {
+	if algorithm.Algorithm.Equal(oidPBES2) {
+		return pbes2Cipher(algorithm.Parameters.FullBytes, password)
+	}
+
+	var params pbeParams
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal pbe parameters: %w", err)
+	}
+
+	if err := checkIterations(params.Iterations); err != nil {
+		return nil, nil, err
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	derive := func(id, size int) []byte {
+		return pkcs12KDF(sha1.New, bmp, params.Salt, params.Iterations, id, size)
+	}
+
+	var (
+		block cipher.Block
+		err   error
+	)
+
+	switch {
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		block, err = des.NewTripleDESCipher(derive(pkcs12KeyID, 24)) //nolint:gomnd,mnd
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd2KeyTripleDESCBC):
+		key := derive(pkcs12KeyID, 16) //nolint:gomnd,mnd
+		block, err = des.NewTripleDESCipher(append(key, key[:8]...))
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		block = newRC2Cipher(derive(pkcs12KeyID, 16), 128) //nolint:gomnd,mnd
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		block = newRC2Cipher(derive(pkcs12KeyID, 5), 40) //nolint:gomnd,mnd
+	default:
+		return nil, nil, fmt.Errorf("got unsupported encryption algorithm %s", algorithm.Algorithm)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	return block, derive(pkcs12IVID, block.BlockSize()), nil
+}
+
+func pbes2Cipher(der, password []byte) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(der, &params); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal pbes2 parameters: %w", err)
+	}
+
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("got unsupported key derivation function %s", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal pbkdf2 parameters: %w", err)
+	}
+
+	if err := checkIterations(kdfParams.IterationCount); err != nil {
+		return nil, nil, err
+	}
+
+	prf, err := digestByHMACOID(kdfParams.PRF.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keyLen int
+
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, nil, fmt.Errorf("got unsupported encryption scheme %s", scheme)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal iv: %w", err)
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, nil, errors.New("got invalid iv length")
+	}
+
+	key := pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, keyLen, prf)
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	return block, iv, nil
+}
+
+// pbeEncrypt encrypts data with PBES2 using PBKDF2 with HMAC-SHA256 and AES-256-CBC.
+func pbeEncrypt(rand io.Reader, data, password []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt := make([]byte, defaultPBESaltLen)
+	iv := make([]byte, aes.BlockSize)
+
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("read salt: %w", err)
+	}
+
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("read iv: %w", err)
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: defaultPBEIterations,
+		KeyLength:      aes256KeyLen,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("marshal pbkdf2 parameters: %w", err)
+	}
+
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("marshal iv: %w", err)
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("marshal pbes2 parameters: %w", err)
+	}
+
+	key := pbkdf2.Key(password, salt, defaultPBEIterations, aes256KeyLen, sha256.New)
+	defer zeroing(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	encrypted := pad(data, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	algorithm := pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}
+
+	return algorithm, encrypted, nil
+}
+
+// pkcs12MAC computes HMAC of data with key derived by PKCS#12 key derivation function.
+func pkcs12MAC(newHash func() hash.Hash, data, bmp, salt []byte, iterations int) []byte {
+	key := pkcs12KDF(newHash, bmp, salt, iterations, pkcs12MACID, newHash().Size())
+	defer zeroing(key)
+
+	mac := hmac.New(newHash, key)
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+
+	padded := make([]byte, len(data), len(data)+n)
+	copy(padded, data)
+
+	return append(padded, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("got empty data")
+	}
+
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("got invalid padding")
+	}
+
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("got invalid padding")
+		}
+	}
+
+	return data[:len(data)-n], nil
+}
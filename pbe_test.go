@@ -0,0 +1,96 @@
+package keystore
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRC2(t *testing.T) {
+	// Test vectors of RFC 2268 section 5.
+	table := []struct {
+		key, plain, cipher string
+		effectiveBits      int
+	}{
+		{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+		{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+		{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+		{"88", "0000000000000000", "61a8a244adacccf0", 64},
+		{"88bca90e90875a", "0000000000000000", "6ccf4308974c267f", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "1a807d272bbe5db1", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	}
+
+	for _, tt := range table {
+		key, _ := hex.DecodeString(tt.key)
+		plain, _ := hex.DecodeString(tt.plain)
+		expected, _ := hex.DecodeString(tt.cipher)
+
+		block := newRC2Cipher(key, tt.effectiveBits)
+		actual := make([]byte, rc2BlockSize)
+
+		block.Encrypt(actual, plain)
+		assert.Equal(t, expected, actual, tt.key)
+
+		block.Decrypt(actual, actual)
+		assert.Equal(t, plain, actual, tt.key)
+	}
+}
+
+func TestBMPPassword(t *testing.T) {
+	assert.Equal(t, []byte{0, 0}, bmpPassword(nil))
+	assert.Equal(t, []byte{0, 'p', 0, 'w', 0, 0}, bmpPassword([]byte("pw")))
+	assert.Equal(t, []byte{0x04, 0x3f, 0, 0}, bmpPassword([]byte("п")))
+}
+
+func TestPBERoundTrip(t *testing.T) {
+	data := []byte("private key")
+
+	algorithm, encrypted, err := pbeEncrypt(rand.Reader, data, []byte("password"))
+	require.NoError(t, err)
+	assert.Len(t, encrypted, 16)
+
+	decrypted, err := pbeDecrypt(algorithm, encrypted, []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+}
+
+func TestPBEIterationLimit(t *testing.T) {
+	for _, iterations := range []int{0, maxPBEIterations + 1} {
+		params, err := asn1.Marshal(pbeParams{Salt: []byte("salt"), Iterations: iterations})
+		require.NoError(t, err)
+
+		algorithm := pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBEWithSHAAnd3KeyTripleDESCBC,
+			Parameters: asn1.RawValue{FullBytes: params},
+		}
+
+		_, err = pbeDecrypt(algorithm, make([]byte, 16), []byte("password"))
+		require.ErrorContains(t, err, "invalid iteration count")
+	}
+
+	algorithm, encrypted, err := pbeEncrypt(rand.Reader, []byte("private key"), []byte("password"))
+	require.NoError(t, err)
+
+	var params pbes2Params
+	_, err = asn1.Unmarshal(algorithm.Parameters.FullBytes, &params)
+	require.NoError(t, err)
+
+	var kdfParams pbkdf2Params
+	_, err = asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams)
+	require.NoError(t, err)
+
+	kdfParams.IterationCount = maxPBEIterations + 1
+	params.KeyDerivationFunc.Parameters.FullBytes, err = asn1.Marshal(kdfParams)
+	require.NoError(t, err)
+	algorithm.Parameters.FullBytes, err = asn1.Marshal(params)
+	require.NoError(t, err)
+
+	_, err = pbeDecrypt(algorithm, encrypted, []byte("password"))
+	require.ErrorContains(t, err, "invalid iteration count")
+}
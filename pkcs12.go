@@ -0,0 +1,766 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const pkcs12Version = 3
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509CertificateBag  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidFriendlyName        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidTrustedKeyUsage     = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+	oidAnyExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+)
+
+type pfxPDU struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// StorePKCS12 writes the keystore as PKCS#12 protected with password the way keytool -importkeystore does.
+// Private keys are decrypted trying passwords from opts and then the store password,
+// ErrIncorrectPassword is returned if any of them can not be decrypted.
+// Keys and certificates are encrypted with PBES2 using PBKDF2 with HMAC-SHA256 and AES-256-CBC,
+// the integrity is protected with HMAC-SHA256. Trusted certificates are marked as trusted for any usage.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) StorePKCS12(w io.Writer, password []byte, opts UnlockOptions) error {
+	if len(password) < ks.minPasswordLen {
+		return fmt.Errorf("password must be at least %d characters: %w", ks.minPasswordLen, ErrShortPassword)
+	}
+
+	opts.Candidates = append(append([][]byte{}, opts.Candidates...), password)
+
+	unlocked := ks.UnlockPrivateKeyEntries(opts)
+	defer unlocked.Zeroing()
+
+	if len(unlocked.Locked) > 0 {
+		return fmt.Errorf("decrypt private key %q: %w", unlocked.Locked[0].Alias, unlocked.Locked[0].Err)
+	}
+
+	var keyBags, certBags []safeBag
+
+	for _, alias := range ks.Aliases() {
+		switch typedEntry := ks.m[alias].(type) {
+		case PrivateKeyEntry:
+			bags, err := ks.privateKeyBags(alias, unlocked.Entries[alias], password)
+			if err != nil {
+				return fmt.Errorf("encode private key entry %q: %w", alias, err)
+			}
+
+			keyBags = append(keyBags, bags[0])
+			certBags = append(certBags, bags[1:]...)
+		case TrustedCertificateEntry:
+			bag, err := newTrustedCertBag(alias, typedEntry)
+			if err != nil {
+				return fmt.Errorf("encode trusted certificate entry %q: %w", alias, err)
+			}
+
+			certBags = append(certBags, bag)
+		default:
+			return errors.New("got invalid entry")
+		}
+	}
+
+	authSafe, err := ks.marshalAuthenticatedSafe(keyBags, certBags, password)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, defaultPBESaltLen)
+	if _, err := io.ReadFull(ks.r, salt); err != nil {
+		return fmt.Errorf("read mac salt: %w", err)
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	pfx := pfxPDU{
+		Version:  pkcs12Version,
+		AuthSafe: newContentInfo(oidData, authSafe),
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+				Digest:    pkcs12MAC(sha256.New, authSafe, bmp, salt, defaultPBEIterations),
+			},
+			MacSalt:    salt,
+			Iterations: defaultPBEIterations,
+		},
+	}
+
+	der, err := asn1.Marshal(pfx)
+	if err != nil {
+		return fmt.Errorf("marshal pfx: %w", err)
+	}
+
+	if _, err := w.Write(der); err != nil {
+		return fmt.Errorf("write pfx: %w", err)
+	}
+
+	return nil
+}
+
+// privateKeyBags returns shrouded key bag followed by certificate bags of the chain.
+// Local key id defaults to SHA-1 of the leaf certificate or of the public key if the chain is empty.
+func (ks KeyStore) privateKeyBags(alias string, pke PrivateKeyEntry, password []byte) ([]safeBag, error) {
+	m := mergeAttributes(pke.Attributes, map[string]string{AttributeFriendlyName: alias})
+	if _, ok := m[AttributeLocalKeyID]; !ok {
+		localKeyID, err := defaultLocalKeyID(pke)
+		if err != nil {
+			return nil, err
+		}
+
+		m[AttributeLocalKeyID] = hex.EncodeToString(localKeyID[:])
+	}
+
+	keyAttributes, err := encodePKCS12Attributes(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// The leaf certificate must not be marked as trusted, otherwise it becomes a trusted certificate entry.
+	delete(m, AttributeTrustedKeyUsage)
+
+	certAttributes, err := encodePKCS12Attributes(m)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, encrypted, err := pbeEncrypt(ks.r, pke.PrivateKey, password)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt private key: %w", err)
+	}
+
+	keyInfo, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: encrypted})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encrypted private key: %w", err)
+	}
+
+	bags := []safeBag{{ID: oidPKCS8ShroudedKeyBag, Value: explicitValue(keyInfo), Attributes: keyAttributes}}
+
+	for i, cert := range pke.CertificateChain {
+		var bag safeBag
+
+		if i == 0 {
+			bag, err = newCertBag(cert, certAttributes...)
+		} else {
+			bag, err = newCertBag(cert)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("encode %d certificate: %w", i, err)
+		}
+
+		bags = append(bags, bag)
+	}
+
+	return bags, nil
+}
+
+func defaultLocalKeyID(pke PrivateKeyEntry) ([sha1.Size]byte, error) {
+	if len(pke.CertificateChain) > 0 {
+		return sha1.Sum(pke.CertificateChain[0].Content), nil
+	}
+
+	key, err := parsePrivateKey(pke.PrivateKey)
+	if err != nil {
+		return [sha1.Size]byte{}, err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return [sha1.Size]byte{}, fmt.Errorf("marshal public key: %w", err)
+	}
+
+	return sha1.Sum(publicKey), nil
+}
+
+// marshalAuthenticatedSafe puts certificates into encrypted content and keys into plain data content.
+func (ks KeyStore) marshalAuthenticatedSafe(keyBags, certBags []safeBag, password []byte) ([]byte, error) {
+	var contents []contentInfo
+
+	if len(certBags) > 0 {
+		safeContents, err := asn1.Marshal(certBags)
+		if err != nil {
+			return nil, fmt.Errorf("marshal certificate bags: %w", err)
+		}
+
+		algorithm, encrypted, err := pbeEncrypt(ks.r, safeContents, password)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt certificate bags: %w", err)
+		}
+
+		ed, err := asn1.Marshal(encryptedData{
+			EncryptedContentInfo: encryptedContentInfo{
+				ContentType:                oidData,
+				ContentEncryptionAlgorithm: algorithm,
+				EncryptedContent:           encrypted,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("marshal encrypted data: %w", err)
+		}
+
+		contents = append(contents, contentInfo{ContentType: oidEncryptedData, Content: explicitValue(ed)})
+	}
+
+	if len(keyBags) > 0 {
+		safeContents, err := asn1.Marshal(keyBags)
+		if err != nil {
+			return nil, fmt.Errorf("marshal key bags: %w", err)
+		}
+
+		contents = append(contents, newContentInfo(oidData, safeContents))
+	}
+
+	authSafe, err := asn1.Marshal(contents)
+	if err != nil {
+		return nil, fmt.Errorf("marshal authenticated safe: %w", err)
+	}
+
+	return authSafe, nil
+}
+
+// LoadPKCS12 reads PKCS#12 representation from r, checks its integrity and adds its entries into the keystore.
+// PKCS#12 without MAC is rejected unless the keystore is created using WithPKCS12WithoutMAC option.
+// Private keys are encrypted with passwords from keyPasswords or with the PKCS#12 password if it is nil or
+// returns nil source. Private keys without certificate get empty chain. Certificates without local key id
+// which are not a part of any chain and certificates marked as trusted become TrustedCertificateEntry.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) LoadPKCS12(r io.Reader, password []byte, keyPasswords KeyPasswordResolver) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read pfx: %w", err)
+	}
+
+	var pfx pfxPDU
+
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return fmt.Errorf("unmarshal pfx: %w", err)
+	}
+
+	if pfx.Version != pkcs12Version {
+		return fmt.Errorf("got unsupported pfx version %d", pfx.Version)
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return fmt.Errorf("got unsupported authenticated safe content type %s", pfx.AuthSafe.ContentType)
+	}
+
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return fmt.Errorf("unmarshal authenticated safe: %w", err)
+	}
+
+	switch {
+	case len(pfx.MacData.Mac.Algorithm.Algorithm) > 0:
+		if err := verifyPKCS12MAC(pfx.MacData, authSafe, password); err != nil {
+			return err
+		}
+	case !ks.pkcs12WithoutMAC:
+		return errors.New("got pfx without mac, use WithPKCS12WithoutMAC option to load it")
+	}
+
+	bags, err := decodeAuthenticatedSafe(authSafe, password)
+	if err != nil {
+		return err
+	}
+
+	entries, err := decodeSafeBags(bags, password)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		for _, entry := range entries {
+			if entry.pke != nil {
+				zeroing(entry.pke.PrivateKey)
+			}
+		}
+	}()
+
+	// Entries are added into the keystore only if all of them are decoded.
+	staging := ks
+	staging.m = make(map[string]interface{}, len(entries))
+
+	for _, entry := range entries {
+		alias := ks.convertAlias(entry.alias)
+		if _, ok := ks.m[alias]; ok {
+			return fmt.Errorf("got duplicate alias %q", entry.alias)
+		}
+
+		if _, ok := staging.m[alias]; ok {
+			return fmt.Errorf("got duplicate alias %q", entry.alias)
+		}
+
+		if entry.tce != nil {
+			staging.m[alias] = *entry.tce
+
+			continue
+		}
+
+		source := PasswordSource(nil)
+		if keyPasswords != nil {
+			source = keyPasswords(entry.alias)
+		}
+
+		if source == nil {
+			err = staging.SetPrivateKeyEntry(entry.alias, *entry.pke, password)
+		} else {
+			err = staging.SetPrivateKeyEntryWithSource(entry.alias, *entry.pke, source)
+		}
+
+		if err != nil {
+			return fmt.Errorf("set private key entry %q: %w", entry.alias, err)
+		}
+	}
+
+	for alias, entry := range staging.m {
+		ks.m[alias] = entry
+	}
+
+	return nil
+}
+
+func verifyPKCS12MAC(md macData, authSafe, password []byte) error {
+	newHash, err := digestByOID(md.Mac.Algorithm.Algorithm)
+	if err != nil {
+		return fmt.Errorf("verify mac: %w", err)
+	}
+
+	if err := checkIterations(md.Iterations); err != nil {
+		return fmt.Errorf("verify mac: %w", err)
+	}
+
+	bmp := bmpPassword(password)
+	defer zeroing(bmp)
+
+	candidates := [][]byte{bmp}
+	if len(password) == 0 {
+		// Some implementations derive key from an empty password without the null terminator.
+		candidates = append(candidates, nil)
+	}
+
+	for _, candidate := range candidates {
+		if hmac.Equal(pkcs12MAC(newHash, authSafe, candidate, md.MacSalt, md.Iterations), md.Mac.Digest) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("got invalid mac: %w", ErrIncorrectPassword)
+}
+
+func decodeAuthenticatedSafe(authSafe, password []byte) ([]safeBag, error) {
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, fmt.Errorf("unmarshal authenticated safe: %w", err)
+	}
+
+	var bags []safeBag
+
+	for i, ci := range contents {
+		var safeContents []byte
+
+		switch {
+		case ci.ContentType.Equal(oidData):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &safeContents); err != nil {
+				return nil, fmt.Errorf("unmarshal %d content: %w", i, err)
+			}
+		case ci.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, fmt.Errorf("unmarshal %d content: %w", i, err)
+			}
+
+			eci := ed.EncryptedContentInfo
+
+			decrypted, err := pbeDecrypt(eci.ContentEncryptionAlgorithm, eci.EncryptedContent, password)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt %d content: %w", i, err)
+			}
+
+			safeContents = decrypted
+		default:
+			return nil, fmt.Errorf("got unsupported content type %s", ci.ContentType)
+		}
+
+		var contentBags []safeBag
+		if _, err := asn1.Unmarshal(safeContents, &contentBags); err != nil {
+			return nil, fmt.Errorf("unmarshal %d safe contents: %w", i, err)
+		}
+
+		bags = append(bags, contentBags...)
+	}
+
+	return bags, nil
+}
+
+type pkcs12Entry struct {
+	alias string
+	pke   *PrivateKeyEntry
+	tce   *TrustedCertificateEntry
+}
+
+type pkcs12Certificate struct {
+	cert         *x509.Certificate
+	friendlyName string
+	localKeyID   []byte
+	trusted      bool
+	attributes   map[string]string
+}
+
+type pkcs12Key struct {
+	der          []byte
+	friendlyName string
+	localKeyID   []byte
+	attributes   map[string]string
+}
+
+// decodeSafeBags matches keys with certificates and returns entries in the order of the bags.
+func decodeSafeBags(bags []safeBag, password []byte) ([]pkcs12Entry, error) {
+	var (
+		keys  []pkcs12Key
+		certs []pkcs12Certificate
+	)
+
+	for i, bag := range bags {
+		attributes, err := decodePKCS12Attributes(bag.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("decode %d bag attributes: %w", i, err)
+		}
+
+		friendlyName := attributes[AttributeFriendlyName]
+		localKeyID, _ := hex.DecodeString(attributes[AttributeLocalKeyID])
+
+		switch {
+		case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+			der, err := decodeKeyBag(bag, password)
+			if err != nil {
+				return nil, fmt.Errorf("decode %d bag: %w", i, err)
+			}
+
+			keys = append(keys, pkcs12Key{der: der, friendlyName: friendlyName, localKeyID: localKeyID, attributes: attributes})
+		case bag.ID.Equal(oidCertBag):
+			var cb certBag
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+				return nil, fmt.Errorf("unmarshal %d certificate bag: %w", i, err)
+			}
+
+			if !cb.ID.Equal(oidX509CertificateBag) {
+				continue
+			}
+
+			cert, err := x509.ParseCertificate(cb.Data)
+			if err != nil {
+				return nil, fmt.Errorf("parse %d certificate: %w", i, err)
+			}
+
+			_, trusted := attributes[AttributeTrustedKeyUsage]
+
+			certs = append(certs, pkcs12Certificate{
+				cert:         cert,
+				friendlyName: friendlyName,
+				localKeyID:   localKeyID,
+				trusted:      trusted,
+				attributes:   attributes,
+			})
+		}
+	}
+
+	return matchKeysAndCertificates(keys, certs)
+}
+
+func matchKeysAndCertificates(keys []pkcs12Key, certs []pkcs12Certificate) ([]pkcs12Entry, error) {
+	var (
+		entries []pkcs12Entry
+		chained []*x509.Certificate
+	)
+
+	candidates := make([]*x509.Certificate, len(certs))
+	for i, c := range certs {
+		candidates[i] = c.cert
+	}
+
+	creationTime := time.Now()
+
+	for i, key := range keys {
+		leaf, found, err := findLeafCertificate(key, certs, len(keys) == 1)
+		if err != nil {
+			return nil, fmt.Errorf("find certificate of %d key: %w", i, err)
+		}
+
+		alias := key.friendlyName
+		if alias == "" {
+			alias = leaf.friendlyName
+		}
+
+		if alias == "" {
+			alias = strconv.Itoa(i + 1)
+		}
+
+		// Keys without certificate become entries with empty chain.
+		var chain []*x509.Certificate
+		if found {
+			chain = buildCertificateChain(leaf.cert, candidates)
+			chained = append(chained, chain...)
+		}
+
+		pke := &PrivateKeyEntry{
+			CreationTime: creationTime,
+			PrivateKey:   key.der,
+			Attributes:   mergeAttributes(leaf.attributes, key.attributes),
+		}
+
+		for _, cert := range chain {
+			pke.CertificateChain = append(pke.CertificateChain, Certificate{Type: X509CertificateType, Content: cert.Raw})
+		}
+
+		entries = append(entries, pkcs12Entry{alias: alias, pke: pke})
+	}
+
+	for _, c := range certs {
+		if !c.trusted && (len(c.localKeyID) > 0 || containsCertificate(chained, c.cert)) {
+			continue
+		}
+
+		alias := c.friendlyName
+		if alias == "" {
+			digest := sha1.Sum(c.cert.Raw)
+			alias = hex.EncodeToString(digest[:])
+		}
+
+		tce := &TrustedCertificateEntry{
+			CreationTime: creationTime,
+			Certificate:  Certificate{Type: X509CertificateType, Content: c.cert.Raw},
+			Attributes:   c.attributes,
+		}
+
+		entries = append(entries, pkcs12Entry{alias: alias, tce: tce})
+	}
+
+	return entries, nil
+}
+
+// findLeafCertificate finds certificate by local key id or by public key if it is the only key.
+func findLeafCertificate(key pkcs12Key, certs []pkcs12Certificate, onlyKey bool) (pkcs12Certificate, bool, error) {
+	if len(key.localKeyID) > 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.localKeyID, key.localKeyID) {
+				return c, true, nil
+			}
+		}
+	}
+
+	if !onlyKey && len(key.localKeyID) > 0 {
+		return pkcs12Certificate{}, false, nil
+	}
+
+	signer, err := parsePrivateKey(key.der)
+	if err != nil {
+		return pkcs12Certificate{}, false, err
+	}
+
+	for _, c := range certs {
+		if publicKeysEqual(c.cert.PublicKey, signer.Public()) {
+			return c, true, nil
+		}
+	}
+
+	return pkcs12Certificate{}, false, nil
+}
+
+func decodeKeyBag(bag safeBag, password []byte) ([]byte, error) {
+	der := bag.Value.Bytes
+
+	if bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+		var keyInfo encryptedPrivateKeyInfo
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &keyInfo); err != nil {
+			return nil, fmt.Errorf("unmarshal encrypted private key: %w", err)
+		}
+
+		decrypted, err := pbeDecrypt(keyInfo.Algorithm, keyInfo.EncryptedData, password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt private key: %w", err)
+		}
+
+		der = decrypted
+	} else {
+		der = append([]byte{}, der...)
+	}
+
+	if _, err := x509.ParsePKCS8PrivateKey(der); err != nil {
+		zeroing(der)
+
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	return der, nil
+}
+
+func newCertBag(cert Certificate, attributes ...pkcs12Attribute) (safeBag, error) {
+	if !isX509CertificateType(cert.Type) {
+		return safeBag{}, fmt.Errorf("got unsupported certificate type %q", cert.Type)
+	}
+
+	der, err := asn1.Marshal(certBag{ID: oidX509CertificateBag, Data: cert.Content})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("marshal certificate bag: %w", err)
+	}
+
+	return safeBag{ID: oidCertBag, Value: explicitValue(der), Attributes: attributes}, nil
+}
+
+func newFriendlyNameAttribute(name string) pkcs12Attribute {
+	value := asn1.RawValue{Tag: asn1.TagBMPString, Bytes: encodeBMPString(name)}
+
+	der, _ := asn1.Marshal(value)
+
+	return pkcs12Attribute{ID: oidFriendlyName, Value: setValue(der)}
+}
+
+func newLocalKeyIDAttribute(id []byte) pkcs12Attribute {
+	der, _ := asn1.Marshal(id)
+
+	return pkcs12Attribute{ID: oidLocalKeyID, Value: setValue(der)}
+}
+
+// newTrustedCertBag returns certificate bag marked as trusted for any usage unless attributes tell the usages.
+func newTrustedCertBag(alias string, tce TrustedCertificateEntry) (safeBag, error) {
+	m := mergeAttributes(tce.Attributes, map[string]string{AttributeFriendlyName: alias})
+	if _, ok := m[AttributeTrustedKeyUsage]; !ok {
+		m[AttributeTrustedKeyUsage] = oidAnyExtendedKeyUsage.String()
+	}
+
+	attributes, err := encodePKCS12Attributes(m)
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	return newCertBag(tce.Certificate, attributes...)
+}
+
+// newTrustedKeyUsageAttribute encodes comma separated dotted OIDs of extended key usages.
+func newTrustedKeyUsageAttribute(usages string) (pkcs12Attribute, error) {
+	var der []byte
+
+	for _, usage := range strings.Split(usages, ",") {
+		oid, err := parseOID(strings.TrimSpace(usage))
+		if err != nil {
+			return pkcs12Attribute{}, fmt.Errorf("parse trusted key usage: %w", err)
+		}
+
+		b, err := asn1.Marshal(oid)
+		if err != nil {
+			return pkcs12Attribute{}, fmt.Errorf("marshal trusted key usage: %w", err)
+		}
+
+		der = append(der, b...)
+	}
+
+	return pkcs12Attribute{ID: oidTrustedKeyUsage, Value: setValue(der)}, nil
+}
+
+func newContentInfo(contentType asn1.ObjectIdentifier, content []byte) contentInfo {
+	octets, _ := asn1.Marshal(content)
+
+	return contentInfo{ContentType: contentType, Content: explicitValue(octets)}
+}
+
+// explicitValue wraps DER encoded value into context specific explicit tag 0.
+func explicitValue(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+func setValue(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der}
+}
+
+func encodeBMPString(s string) []byte {
+	codes := utf16.Encode([]rune(s))
+	out := make([]byte, 0, len(codes)*bmpCharLen)
+
+	for _, c := range codes {
+		out = append(out, byte(c>>8), byte(c)) //nolint:gomnd,mnd
+	}
+
+	return out
+}
+
+func decodeBMPString(b []byte) (string, error) {
+	if len(b)%bmpCharLen != 0 {
+		return "", errors.New("got odd length of BMPString")
+	}
+
+	codes := make([]uint16, 0, len(b)/bmpCharLen)
+	for i := 0; i < len(b); i += bmpCharLen {
+		codes = append(codes, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+
+	return string(utf16.Decode(codes)), nil
+}
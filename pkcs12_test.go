@@ -0,0 +1,287 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPKCS12(t *testing.T) {
+	table := []struct {
+		name     string
+		filename string
+		chainLen int
+	}{
+		{name: "pbes2 aes-256", filename: "./testdata/keystore.p12", chainLen: 2},
+		{name: "rc2-40 and 3des", filename: "./testdata/keystore_legacy.p12", chainLen: 2},
+		{name: "rc2-128 and 2-key 3des with sha1 mac", filename: "./testdata/keystore_rc2_128.p12", chainLen: 1},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.filename)
+			require.NoError(t, err)
+
+			defer f.Close()
+
+			ks := New()
+			require.NoError(t, ks.LoadPKCS12(f, []byte("password"), nil))
+			assert.Equal(t, []string{"server"}, ks.Aliases())
+
+			pke, err := ks.GetPrivateKeyEntry("server", []byte("password"))
+			require.NoError(t, err)
+			require.Len(t, pke.CertificateChain, tt.chainLen)
+
+			leaf, err := x509.ParseCertificate(pke.CertificateChain[0].Content)
+			require.NoError(t, err)
+			assert.Equal(t, "CN=localhost", leaf.Subject.String())
+
+			key, err := parsePrivateKey(pke.PrivateKey)
+			require.NoError(t, err)
+			assert.True(t, publicKeysEqual(leaf.PublicKey, key.Public()))
+		})
+	}
+}
+
+func TestLoadPKCS12Errors(t *testing.T) {
+	data, err := os.ReadFile("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	err = New().LoadPKCS12(bytes.NewReader(data), []byte("wrong"), nil)
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	err = New().LoadPKCS12(bytes.NewReader(data[:len(data)/2]), []byte("password"), nil)
+	require.Error(t, err)
+
+	ks := New()
+	require.NoError(t, ks.LoadPKCS12(bytes.NewReader(data), []byte("password"), nil))
+	require.Error(t, ks.LoadPKCS12(bytes.NewReader(data), []byte("password"), nil), "duplicate alias")
+}
+
+func TestLoadPKCS12IterationLimit(t *testing.T) {
+	data, err := os.ReadFile("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	var pfx pfxPDU
+
+	_, err = asn1.Unmarshal(data, &pfx)
+	require.NoError(t, err)
+
+	pfx.MacData.Iterations = maxPBEIterations + 1
+
+	crafted, err := asn1.Marshal(pfx)
+	require.NoError(t, err)
+
+	err = New().LoadPKCS12(bytes.NewReader(crafted), []byte("password"), nil)
+	require.ErrorContains(t, err, "invalid iteration count")
+}
+
+func TestLoadPKCS12WithoutMAC(t *testing.T) {
+	data, err := os.ReadFile("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	var pfx pfxPDU
+
+	_, err = asn1.Unmarshal(data, &pfx)
+	require.NoError(t, err)
+
+	pfx.MacData = macData{}
+
+	crafted, err := asn1.Marshal(pfx)
+	require.NoError(t, err)
+	require.Less(t, len(crafted), len(data))
+
+	ks := New()
+	err = ks.LoadPKCS12(bytes.NewReader(crafted), []byte("password"), nil)
+	require.ErrorContains(t, err, "without mac")
+	assert.Empty(t, ks.Aliases())
+
+	ks = New(WithPKCS12WithoutMAC())
+	require.NoError(t, ks.LoadPKCS12(bytes.NewReader(crafted), []byte("password"), nil))
+	assert.NotEmpty(t, ks.Aliases())
+
+	err = New(WithPKCS12WithoutMAC()).LoadPKCS12(bytes.NewReader(crafted), []byte("wrong"), nil)
+	require.Error(t, err)
+}
+
+func TestLoadPKCS12KeepsKeyStoreOnError(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("server", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: "X509", Content: readCertificate(t)}},
+	}, []byte("password")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("root", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.StorePKCS12(&buf, []byte("password"), UnlockOptions{}))
+
+	errSource := errors.New("source")
+
+	loaded := New()
+	err := loaded.LoadPKCS12(&buf, []byte("password"), KeyPasswords(nil,
+		PasswordFunc(func() ([]byte, error) { return nil, errSource })))
+	require.ErrorIs(t, err, errSource)
+	assert.Empty(t, loaded.Aliases())
+}
+
+func TestLoadPKCS12TrustStore(t *testing.T) {
+	f, err := os.Open("./testdata/truststore.p12")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	ks := New()
+	require.NoError(t, ks.LoadPKCS12(f, []byte("password"), nil))
+
+	aliases := ks.Aliases()
+	require.Len(t, aliases, 1)
+	assert.True(t, ks.IsTrustedCertificateEntry(aliases[0]))
+}
+
+func TestLoadPKCS12KeyPasswords(t *testing.T) {
+	f, err := os.Open("./testdata/keystore.p12")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	ks := New()
+	keyPassword := PasswordFunc(func() ([]byte, error) { return []byte("keypassword"), nil })
+
+	require.NoError(t, ks.LoadPKCS12(f, []byte("password"), KeyPasswords(map[string]PasswordSource{
+		"server": keyPassword,
+	}, nil)))
+
+	_, err = ks.GetPrivateKeyEntry("server", []byte("keypassword"))
+	require.NoError(t, err)
+}
+
+func TestStorePKCS12RoundTrip(t *testing.T) {
+	key := generateTestKey(t)
+	rootTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	root := createTestCertificate(t, rootTemplate, rootTemplate, &key.PublicKey, key)
+
+	ks := New(WithOrderedAliases())
+
+	pke, err := GeneratePrivateKeyEntry(rand.Reader, KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "server"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ks.SetPrivateKeyEntry("Server", pke, []byte("keypassword")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("root", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: root.Raw},
+	}))
+
+	var buf bytes.Buffer
+
+	err = ks.StorePKCS12(&buf, []byte("password"), UnlockOptions{})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	err = ks.StorePKCS12(&buf, []byte("password"), UnlockOptions{Passwords: map[string][]byte{
+		"server": []byte("keypassword"),
+	}})
+	require.NoError(t, err)
+
+	assertTrustedKeyUsage(t, buf.Bytes())
+
+	loaded := New(WithOrderedAliases())
+	require.NoError(t, loaded.LoadPKCS12(&buf, []byte("password"), nil))
+	assert.Equal(t, []string{"root", "server"}, loaded.Aliases())
+
+	actual, err := loaded.GetPrivateKeyEntry("server", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, pke.PrivateKey, actual.PrivateKey)
+	assert.Equal(t, pke.CertificateChain, actual.CertificateChain)
+
+	tce, err := loaded.GetTrustedCertificateEntry("root")
+	require.NoError(t, err)
+	assert.Equal(t, root.Raw, tce.Certificate.Content)
+}
+
+func TestStorePKCS12UnsupportedCertificateType(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetTrustedCertificateEntry("other", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "other", Content: []byte{1, 2, 3}},
+	}))
+
+	require.Error(t, ks.StorePKCS12(&bytes.Buffer{}, []byte("password"), UnlockOptions{}))
+}
+
+func assertTrustedKeyUsage(t *testing.T, data []byte) {
+	t.Helper()
+
+	var pfx pfxPDU
+
+	_, err := asn1.Unmarshal(data, &pfx)
+	require.NoError(t, err)
+
+	var authSafe []byte
+
+	_, err = asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe)
+	require.NoError(t, err)
+
+	bags, err := decodeAuthenticatedSafe(authSafe, []byte("password"))
+	require.NoError(t, err)
+
+	var trusted int
+
+	for _, bag := range bags {
+		for _, attribute := range bag.Attributes {
+			if !attribute.ID.Equal(oidTrustedKeyUsage) {
+				continue
+			}
+
+			var usage asn1.ObjectIdentifier
+
+			_, err := asn1.Unmarshal(attribute.Value.Bytes, &usage)
+			require.NoError(t, err)
+			assert.Equal(t, oidAnyExtendedKeyUsage, usage)
+
+			trusted++
+		}
+	}
+
+	assert.Equal(t, 1, trusted)
+}
+
+func TestStorePKCS12WithoutChain(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   readPrivateKey(t),
+	}, []byte("password")))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.StorePKCS12(&buf, []byte("password"), UnlockOptions{}))
+
+	loaded := New()
+	require.NoError(t, loaded.LoadPKCS12(&buf, []byte("password"), nil))
+
+	pke, err := loaded.GetPrivateKeyEntry("key", []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, readPrivateKey(t), pke.PrivateKey)
+	assert.Empty(t, pke.CertificateChain)
+}
@@ -0,0 +1,55 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// parsePKCS7Certificates returns certificates carried by DER encoded PKCS#7 SignedData, e.g. a .p7b file.
+func parsePKCS7Certificates(der []byte) ([]*x509.Certificate, error) {
+	var ci contentInfo
+
+	rest, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal content info: %w", err)
+	}
+
+	if len(rest) > 0 {
+		return nil, errors.New("got extra data in content info")
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("got unsupported content type %s", ci.ContentType)
+	}
+
+	var sd signedData
+
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("unmarshal signed data: %w", err)
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificates: %w", err)
+	}
+
+	return certs, nil
+}
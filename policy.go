@@ -0,0 +1,309 @@
+package keystore
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy checks store passwords used by Store and key passwords used by SetPrivateKeyEntry,
+// see WithPasswordPolicy. BasicPasswordPolicy is the built-in implementation.
+type PasswordPolicy interface {
+	// CheckPassword returns *PasswordPolicyError if the password violates the policy.
+	CheckPassword(check PasswordCheck) error
+}
+
+// PasswordUsage tells which password is checked.
+type PasswordUsage int
+
+const (
+	PasswordUsageStore PasswordUsage = iota
+	PasswordUsageKey
+)
+
+// PasswordCheck is a password to check by PasswordPolicy.
+type PasswordCheck struct {
+	Usage PasswordUsage
+	// Alias is the entry alias for key passwords.
+	Alias    string
+	Password []byte
+	// SharedWith are aliases of private key entries encrypted with the store password.
+	// It is set for store passwords only.
+	SharedWith []string
+}
+
+// PasswordRule names a rule of the password policy.
+type PasswordRule string
+
+const (
+	PasswordRuleLength           PasswordRule = "length"
+	PasswordRuleCharacterClasses PasswordRule = "character classes"
+	PasswordRuleBanned           PasswordRule = "banned"
+	PasswordRuleEntropy          PasswordRule = "entropy"
+	PasswordRuleShared           PasswordRule = "shared"
+)
+
+// PasswordViolation is a rule the password violates.
+type PasswordViolation struct {
+	Rule PasswordRule
+	// Detail tells what is wrong with the password, it never contains the password.
+	Detail string
+}
+
+// PasswordPolicyError lists rules the password violates. It matches ErrPasswordPolicy.
+type PasswordPolicyError struct {
+	Usage      PasswordUsage
+	Alias      string
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	details := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		details[i] = fmt.Sprintf("%s: %s", v.Rule, v.Detail)
+	}
+
+	subject := "store password"
+	if e.Usage == PasswordUsageKey {
+		subject = fmt.Sprintf("key password of %q", e.Alias)
+	}
+
+	return fmt.Sprintf("%s violates password policy: %s", subject, strings.Join(details, "; "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// CharacterClass is a set of character classes. Characters which are not lower case or upper case
+// letters or digits are symbols.
+type CharacterClass int
+
+const (
+	CharacterClassLower CharacterClass = 1 << iota
+	CharacterClassUpper
+	CharacterClassDigit
+	CharacterClassSymbol
+)
+
+// characterClassSizes are numbers of ASCII characters in the classes used to estimate entropy.
+var characterClassSizes = []struct {
+	class CharacterClass
+	name  string
+	size  int
+}{
+	{CharacterClassLower, "lower case letter", 26},
+	{CharacterClassUpper, "upper case letter", 26},
+	{CharacterClassDigit, "digit", 10},
+	{CharacterClassSymbol, "symbol", 33},
+}
+
+// DefaultBannedPasswords are well-known default keystore passwords.
+var DefaultBannedPasswords = []string{"changeit", "changeme", "password", "secret", "keystore", "123456"}
+
+// BasicPasswordPolicy checks the password against the rules which are set.
+type BasicPasswordPolicy struct {
+	// MinLength is the minimal number of characters.
+	MinLength int
+	// RequiredClasses must all be present in the password.
+	RequiredClasses CharacterClass
+	// Banned passwords are compared case-insensitively, see DefaultBannedPasswords.
+	Banned []string
+	// MinEntropy is the minimal estimated entropy in bits. Entropy is estimated as the number of distinct
+	// characters multiplied by log2 of the total size of the character classes the password uses.
+	MinEntropy float64
+	// DistinctPasswords forbids to encrypt private key entries with the store password.
+	// It is checked by Store since the store password is not known when the entries are set.
+	DistinctPasswords bool
+}
+
+// CheckPassword returns *PasswordPolicyError with every rule the password violates.
+func (p BasicPasswordPolicy) CheckPassword(check PasswordCheck) error {
+	var violations []PasswordViolation
+
+	if utf8.RuneCount(check.Password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:   PasswordRuleLength,
+			Detail: fmt.Sprintf("must be at least %d characters", p.MinLength),
+		})
+	}
+
+	classes := characterClasses(check.Password)
+	if missing := p.RequiredClasses &^ classes; missing != 0 {
+		violations = append(violations, PasswordViolation{
+			Rule:   PasswordRuleCharacterClasses,
+			Detail: "must contain " + characterClassNames(missing),
+		})
+	}
+
+	for _, banned := range p.Banned {
+		if bytes.EqualFold(check.Password, []byte(banned)) {
+			violations = append(violations, PasswordViolation{Rule: PasswordRuleBanned, Detail: "is a banned password"})
+
+			break
+		}
+	}
+
+	if entropy := estimateEntropy(check.Password, classes); entropy < p.MinEntropy {
+		violations = append(violations, PasswordViolation{
+			Rule:   PasswordRuleEntropy,
+			Detail: fmt.Sprintf("has estimated entropy of %.0f bits, at least %.0f bits required", entropy, p.MinEntropy),
+		})
+	}
+
+	if p.DistinctPasswords && len(check.SharedWith) > 0 {
+		violations = append(violations, PasswordViolation{
+			Rule:   PasswordRuleShared,
+			Detail: fmt.Sprintf("is the key password of %s", strings.Join(check.SharedWith, ", ")),
+		})
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &PasswordPolicyError{Usage: check.Usage, Alias: check.Alias, Violations: violations}
+}
+
+func characterClasses(password []byte) CharacterClass {
+	var classes CharacterClass
+
+	for rest := password; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+
+		switch {
+		case unicode.IsLower(r):
+			classes |= CharacterClassLower
+		case unicode.IsUpper(r):
+			classes |= CharacterClassUpper
+		case unicode.IsDigit(r):
+			classes |= CharacterClassDigit
+		default:
+			classes |= CharacterClassSymbol
+		}
+	}
+
+	return classes
+}
+
+func characterClassNames(classes CharacterClass) string {
+	var names []string
+
+	for _, c := range characterClassSizes {
+		if classes&c.class != 0 {
+			names = append(names, c.name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func estimateEntropy(password []byte, classes CharacterClass) float64 {
+	var size int
+
+	for _, c := range characterClassSizes {
+		if classes&c.class != 0 {
+			size += c.size
+		}
+	}
+
+	if size == 0 {
+		return 0
+	}
+
+	return float64(distinctCharacters(password)) * math.Log2(float64(size))
+}
+
+func distinctCharacters(password []byte) int {
+	seen := make([]rune, 0, utf8.RuneCount(password))
+	defer func() {
+		for i := range seen {
+			seen[i] = 0
+		}
+	}()
+
+	for rest := password; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+
+		if !slices.Contains(seen, r) {
+			seen = append(seen, r)
+		}
+	}
+
+	return len(seen)
+}
+
+// checkStorePassword checks the store password with the password policy of the keystore.
+func (ks KeyStore) checkStorePassword(password []byte) error {
+	if ks.policy == nil {
+		return nil
+	}
+
+	check := PasswordCheck{Usage: PasswordUsageStore, Password: password}
+
+	if checksSharedPasswords(ks.policy) {
+		check.SharedWith = ks.aliasesEncryptedWith(password)
+	}
+
+	if err := ks.policy.CheckPassword(check); err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+
+	return nil
+}
+
+// aliasesEncryptedWith returns sorted aliases of private key entries the password decrypts.
+// Decrypted keys are destroyed right away.
+func (ks KeyStore) aliasesEncryptedWith(password []byte) []string {
+	var aliases []string
+
+	for _, alias := range ks.Aliases() {
+		pke, ok := ks.m[alias].(PrivateKeyEntry)
+		if !ok {
+			continue
+		}
+
+		if plainKey, err := ks.keyProtector().decrypt(pke.PrivateKey, password); err == nil {
+			destroySecret(plainKey)
+
+			aliases = append(aliases, alias)
+		}
+	}
+
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// checksSharedPasswords tells if the policy needs PasswordCheck.SharedWith. Policies other than
+// BasicPasswordPolicy always get it.
+func checksSharedPasswords(policy PasswordPolicy) bool {
+	switch p := policy.(type) {
+	case BasicPasswordPolicy:
+		return p.DistinctPasswords
+	case *BasicPasswordPolicy:
+		return p.DistinctPasswords
+	default:
+		return true
+	}
+}
+
+// checkKeyPassword checks the key password with the password policy of the keystore.
+func (ks KeyStore) checkKeyPassword(alias string, password []byte) error {
+	if ks.policy == nil {
+		return nil
+	}
+
+	if err := ks.policy.CheckPassword(PasswordCheck{Usage: PasswordUsageKey, Alias: alias, Password: password}); err != nil {
+		return fmt.Errorf("check password: %w", err)
+	}
+
+	return nil
+}
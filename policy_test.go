@@ -0,0 +1,126 @@
+package keystore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBasicPasswordPolicy(t *testing.T) {
+	policy := BasicPasswordPolicy{
+		MinLength:       10,
+		RequiredClasses: CharacterClassLower | CharacterClassUpper | CharacterClassDigit,
+		Banned:          DefaultBannedPasswords,
+		MinEntropy:      60,
+	}
+
+	table := []struct {
+		password string
+		rules    []PasswordRule
+	}{
+		{password: "Tr0ub4dor&3xyz"},
+		{password: "Pässwörd密码😀12"},
+		{password: "ChangeIt", rules: []PasswordRule{
+			PasswordRuleLength, PasswordRuleCharacterClasses, PasswordRuleBanned, PasswordRuleEntropy,
+		}},
+		{password: "aaaaaaaaaaaaaaa", rules: []PasswordRule{PasswordRuleCharacterClasses, PasswordRuleEntropy}},
+		{password: "", rules: []PasswordRule{PasswordRuleLength, PasswordRuleCharacterClasses, PasswordRuleEntropy}},
+	}
+
+	for _, tt := range table {
+		err := policy.CheckPassword(PasswordCheck{Usage: PasswordUsageKey, Alias: "alias", Password: []byte(tt.password)})
+		if len(tt.rules) == 0 {
+			require.NoError(t, err, tt.password)
+
+			continue
+		}
+
+		require.ErrorIs(t, err, ErrPasswordPolicy, tt.password)
+
+		var policyErr *PasswordPolicyError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, PasswordUsageKey, policyErr.Usage)
+		assert.Equal(t, "alias", policyErr.Alias)
+
+		rules := make([]PasswordRule, len(policyErr.Violations))
+		for i, v := range policyErr.Violations {
+			rules[i] = v.Rule
+		}
+
+		assert.Equal(t, tt.rules, rules, tt.password)
+
+		if tt.password != "" {
+			assert.NotContains(t, err.Error(), tt.password)
+		}
+	}
+
+	err := BasicPasswordPolicy{RequiredClasses: CharacterClassUpper | CharacterClassSymbol}.
+		CheckPassword(PasswordCheck{Password: []byte("lower")})
+	require.EqualError(t, err,
+		"store password violates password policy: character classes: must contain upper case letter, symbol")
+}
+
+func TestWithPasswordPolicy(t *testing.T) {
+	policy := BasicPasswordPolicy{MinLength: 8, Banned: DefaultBannedPasswords, DistinctPasswords: true}
+	ks := New(WithPasswordPolicy(policy))
+
+	pke := PrivateKeyEntry{CreationTime: time.Now(), PrivateKey: readPrivateKey(t)}
+
+	err := ks.SetPrivateKeyEntry("weak", pke, []byte("changeit"))
+	require.ErrorIs(t, err, ErrPasswordPolicy)
+	assert.False(t, ks.IsPrivateKeyEntry("weak"))
+
+	require.NoError(t, ks.SetPrivateKeyEntry("b", pke, []byte("store-password")))
+	require.NoError(t, ks.SetPrivateKeyEntry("a", pke, []byte("store-password")))
+	require.NoError(t, ks.SetPrivateKeyEntry("c", pke, []byte("key-password")))
+
+	var buf bytes.Buffer
+
+	err = ks.Store(&buf, []byte("password"))
+	require.ErrorIs(t, err, ErrPasswordPolicy)
+
+	err = ks.Store(&buf, []byte("store-password"))
+
+	var policyErr *PasswordPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []PasswordViolation{{Rule: PasswordRuleShared, Detail: "is the key password of a, b"}},
+		policyErr.Violations)
+	assert.Zero(t, buf.Len())
+
+	ks.DeleteEntry("a")
+	ks.DeleteEntry("b")
+	require.NoError(t, ks.Store(&buf, []byte("store-password")))
+}
+
+type recordingPasswordPolicy struct {
+	checks *[]PasswordCheck
+}
+
+func (p recordingPasswordPolicy) CheckPassword(check PasswordCheck) error {
+	*p.checks = append(*p.checks, check)
+
+	return nil
+}
+
+func TestStoreSharedPasswordScan(t *testing.T) {
+	pke := PrivateKeyEntry{CreationTime: time.Now(), PrivateKey: readPrivateKey(t)}
+
+	var checks []PasswordCheck
+
+	ks := New(WithPasswordPolicy(recordingPasswordPolicy{checks: &checks}))
+	require.NoError(t, ks.SetPrivateKeyEntry("a", pke, []byte("store-password")))
+	require.NoError(t, ks.Store(&bytes.Buffer{}, []byte("store-password")))
+	require.Len(t, checks, 2)
+	assert.Equal(t, []string{"a"}, checks[1].SharedWith)
+
+	ks = New(WithPasswordPolicy(BasicPasswordPolicy{MinLength: 8}))
+	require.NoError(t, ks.SetPrivateKeyEntry("a", pke, []byte("store-password")))
+	require.NoError(t, ks.Store(&bytes.Buffer{}, []byte("store-password")))
+
+	assert.False(t, checksSharedPasswords(BasicPasswordPolicy{}))
+	assert.True(t, checksSharedPasswords(&BasicPasswordPolicy{DistinctPasswords: true}))
+	assert.True(t, checksSharedPasswords(recordingPasswordPolicy{}))
+}
@@ -0,0 +1,196 @@
+package keystore
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// KeySigner is crypto.Signer and crypto.Decrypter backed by PrivateKeyEntry which is kept encrypted.
+// The private key is decrypted with password from the source inside every Sign and Decrypt call and the
+// plaintext is filled with zeros right after it is parsed, unless caching is enabled with WithKeyCache.
+// KeySigner is safe for concurrent use.
+type KeySigner struct {
+	encryptedKey []byte
+	source       PasswordSource
+	public       crypto.PublicKey
+	protector    keyProtector
+
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	key      crypto.Signer
+	lastUsed time.Time
+	timer    *time.Timer
+}
+
+// SignerOption configures KeySigner.
+type SignerOption func(s *KeySigner)
+
+// WithKeyCache keeps the decrypted private key in memory until it is not used for idleTimeout.
+func WithKeyCache(idleTimeout time.Duration) SignerOption {
+	return func(s *KeySigner) { s.idleTimeout = idleTimeout }
+}
+
+// Signer returns KeySigner for PrivateKeyEntry from the keystore by the alias. The entry is copied,
+// so the signer is not affected by later changes of the keystore. The source is asked for the key
+// password every time the private key is decrypted, it is checked by decrypting the key once here.
+func (ks KeyStore) Signer(alias string, source PasswordSource, opts ...SignerOption) (*KeySigner, error) {
+	e, ok := ks.m[ks.convertAlias(alias)]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+
+	pke, ok := e.(PrivateKeyEntry)
+	if !ok {
+		return nil, ErrWrongEntryType
+	}
+
+	s := &KeySigner{
+		encryptedKey: cloneBytes(pke.PrivateKey),
+		source:       source,
+		protector:    ks.keyProtector(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	key, err := s.decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	s.public = key.Public()
+
+	if s.idleTimeout > 0 {
+		s.mu.Lock()
+		s.cache(key)
+		s.mu.Unlock()
+	}
+
+	return s, nil
+}
+
+// Public returns the public key of the private key.
+func (s *KeySigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign decrypts the private key and signs digest with it, see crypto.Signer.
+func (s *KeySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	key, err := s.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return key.Sign(rand, digest, opts)
+}
+
+// Decrypt decrypts the private key and decrypts msg with it, see crypto.Decrypter.
+// ErrUnsupportedKeyAlgorithm is returned for keys which can not decrypt, e.g. ECDSA.
+func (s *KeySigner) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	key, err := s.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	decrypter, ok := key.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("%T: %w", key, ErrUnsupportedKeyAlgorithm)
+	}
+
+	return decrypter.Decrypt(rand, msg, opts)
+}
+
+// Close drops the cached private key. The signer can still be used, the key is decrypted again.
+func (s *KeySigner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+
+	return nil
+}
+
+func (s *KeySigner) privateKey() (crypto.Signer, error) {
+	if s.idleTimeout <= 0 {
+		return s.decrypt()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != nil {
+		s.lastUsed = time.Now()
+
+		return s.key, nil
+	}
+
+	key, err := s.decrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache(key)
+
+	return key, nil
+}
+
+func (s *KeySigner) decrypt() (crypto.Signer, error) {
+	password, err := s.source.Password()
+	if err != nil {
+		return nil, fmt.Errorf("get password: %w", err)
+	}
+
+	defer zeroing(password)
+
+	plainKey, err := s.protector.decrypt(s.encryptedKey, password)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+
+	defer destroySecret(plainKey)
+
+	return parsePrivateKey(plainKey)
+}
+
+// cache must be called with mu held.
+func (s *KeySigner) cache(key crypto.Signer) {
+	s.key = key
+	s.lastUsed = time.Now()
+
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.idleTimeout, s.expire)
+	}
+}
+
+func (s *KeySigner) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer == nil {
+		return
+	}
+
+	if idle := time.Since(s.lastUsed); idle < s.idleTimeout {
+		s.timer.Reset(s.idleTimeout - idle)
+
+		return
+	}
+
+	s.timer = nil
+	s.key = nil
+}
+
+// evict must be called with mu held.
+func (s *KeySigner) evict() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	s.key = nil
+}
@@ -0,0 +1,136 @@
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerDecryptsOnEveryCall(t *testing.T) {
+	ks := New()
+	key := generateTestKey(t)
+	setTestSignerEntry(t, ks, key)
+
+	var calls atomic.Int32
+
+	s, err := ks.Signer("key", countingPassword(&calls))
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(s.Public()))
+
+	digest := sha256.Sum256([]byte("message"))
+	signature, err := s.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature))
+	assert.Equal(t, int32(2), calls.Load())
+
+	_, err = s.Decrypt(rand.Reader, []byte{1}, nil)
+	require.ErrorIs(t, err, ErrUnsupportedKeyAlgorithm)
+	assert.Equal(t, int32(3), calls.Load())
+
+	ks.DeleteEntry("key")
+
+	_, err = s.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+}
+
+func TestSignerDecrypt(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       readPrivateKey(t),
+		CertificateChain: []Certificate{{Type: X509CertificateType, Content: readCertificate(t)}},
+	}, []byte("password")))
+
+	s, err := ks.Signer("key", PasswordFunc(func() ([]byte, error) { return []byte("password"), nil }))
+	require.NoError(t, err)
+
+	ciphertext, err := rsa.EncryptPKCS1v15(rand.Reader, s.Public().(*rsa.PublicKey), []byte("secret"))
+	require.NoError(t, err)
+
+	plaintext, err := s.Decrypt(rand.Reader, ciphertext, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+}
+
+func TestSignerKeyCache(t *testing.T) {
+	ks := New()
+	setTestSignerEntry(t, ks, generateTestKey(t))
+
+	var calls atomic.Int32
+
+	s, err := ks.Signer("key", countingPassword(&calls), WithKeyCache(time.Hour))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("message"))
+	for i := 0; i < 3; i++ {
+		_, err = s.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), calls.Load())
+
+	require.NoError(t, s.Close())
+
+	_, err = s.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	require.NoError(t, s.Close())
+
+	s, err = ks.Signer("key", countingPassword(&calls), WithKeyCache(10*time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.key == nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSignerErrors(t *testing.T) {
+	ks := New()
+	setTestSignerEntry(t, ks, generateTestKey(t))
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: X509CertificateType, Content: readCertificate(t)},
+	}))
+
+	_, err := ks.Signer("missing", nil)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+
+	_, err = ks.Signer("cert", nil)
+	require.ErrorIs(t, err, ErrWrongEntryType)
+
+	_, err = ks.Signer("key", PasswordFunc(func() ([]byte, error) { return []byte("wrong"), nil }))
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func setTestSignerEntry(t *testing.T, ks KeyStore, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       der,
+		CertificateChain: []Certificate{{Type: X509CertificateType, Content: readCertificate(t)}},
+	}, []byte("password")))
+}
+
+func countingPassword(calls *atomic.Int32) PasswordSource {
+	return PasswordFunc(func() ([]byte, error) {
+		calls.Add(1)
+
+		return []byte("password"), nil
+	})
+}
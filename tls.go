@@ -0,0 +1,159 @@
+package keystore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TLSIdentities selects identities of PrivateKeyEntry entries for TLS handshakes.
+// Use GetClientCertificate and GetCertificate as the callbacks of tls.Config with the same names.
+type TLSIdentities struct {
+	identities []tlsIdentity
+}
+
+type tlsIdentity struct {
+	alias       string
+	certificate tls.Certificate
+}
+
+// TLSIdentities decrypts every private key entry trying passwords from opts and returns identities
+// for TLS handshakes. ErrIncorrectPassword is returned if any of the entries can not be decrypted.
+// Entries with certificates of types other than X.509 can not be used for TLS and are skipped.
+func (ks KeyStore) TLSIdentities(opts UnlockOptions) (*TLSIdentities, error) {
+	unlocked := ks.UnlockPrivateKeyEntries(opts)
+	defer unlocked.Zeroing()
+
+	if len(unlocked.Locked) > 0 {
+		return nil, fmt.Errorf("decrypt private key %q: %w", unlocked.Locked[0].Alias, unlocked.Locked[0].Err)
+	}
+
+	ids := &TLSIdentities{}
+
+	for _, alias := range ks.Aliases() {
+		pke, ok := unlocked.Entries[alias]
+		if !ok || len(pke.CertificateChain) == 0 {
+			continue
+		}
+
+		certificate, err := newTLSCertificate(pke)
+		if errors.Is(err, ErrUnsupportedCertificateType) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("load identity %q: %w", alias, err)
+		}
+
+		ids.identities = append(ids.identities, tlsIdentity{alias: alias, certificate: certificate})
+	}
+
+	return ids, nil
+}
+
+func newTLSCertificate(pke PrivateKeyEntry) (tls.Certificate, error) {
+	var certificate tls.Certificate
+
+	for i, c := range pke.CertificateChain {
+		if !isX509CertificateType(c.Type) {
+			return tls.Certificate{}, fmt.Errorf("%d certificate: %w", i, ErrUnsupportedCertificateType)
+		}
+
+		certificate.Certificate = append(certificate.Certificate, append([]byte{}, c.Content...))
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	signer, err := parsePrivateKey(pke.PrivateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if !publicKeysEqual(leaf.PublicKey, signer.Public()) {
+		return tls.Certificate{}, ErrPublicKeyMismatch
+	}
+
+	certificate.Leaf = leaf
+	certificate.PrivateKey = signer
+
+	return certificate, nil
+}
+
+// GetClientCertificate picks the identity the server accepts by its certificate authorities and
+// signature schemes. Currently valid identities are preferred, then the ones which expire later.
+// No certificate is sent if none of the identities is accepted.
+func (ids *TLSIdentities) GetClientCertificate(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if id := ids.best(func(c *tls.Certificate) bool { return cri.SupportsCertificate(c) == nil }); id != nil {
+		return &id.certificate, nil
+	}
+
+	return &tls.Certificate{}, nil
+}
+
+// GetCertificate picks the identity which leaf certificate is valid for the server name the client
+// asked for with SNI and which the client supports. Any supported identity is picked if the client
+// did not send the server name. Identities are preferred the same way GetClientCertificate does.
+func (ids *TLSIdentities) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	id := ids.best(func(c *tls.Certificate) bool {
+		if hello.ServerName != "" && c.Leaf.VerifyHostname(hello.ServerName) != nil {
+			return false
+		}
+
+		return hello.SupportsCertificate(c) == nil
+	})
+	if id == nil {
+		return nil, fmt.Errorf("server name %q: %w", hello.ServerName, ErrEntryNotFound)
+	}
+
+	return &id.certificate, nil
+}
+
+// Aliases returns aliases of the identities.
+func (ids *TLSIdentities) Aliases() []string {
+	aliases := make([]string, len(ids.identities))
+	for i, id := range ids.identities {
+		aliases[i] = id.alias
+	}
+
+	return aliases
+}
+
+func (ids *TLSIdentities) best(accept func(c *tls.Certificate) bool) *tlsIdentity {
+	var (
+		best    *tlsIdentity
+		isValid = ValidAt(time.Now())
+	)
+
+	for i := range ids.identities {
+		id := &ids.identities[i]
+		if !accept(&id.certificate) {
+			continue
+		}
+
+		if best == nil {
+			best = id
+
+			continue
+		}
+
+		leaf, bestLeaf := id.certificate.Leaf, best.certificate.Leaf
+		if valid, bestValid := isValid(leaf), isValid(bestLeaf); valid != bestValid {
+			if valid {
+				best = id
+			}
+
+			continue
+		}
+
+		if leaf.NotAfter.After(bestLeaf.NotAfter) {
+			best = id
+		}
+	}
+
+	return best
+}
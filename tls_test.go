@@ -0,0 +1,166 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSIdentitiesClientCertificate(t *testing.T) {
+	caKey := generateTestKey(t)
+	ca := newTestCA(t, "ca", caKey)
+	otherKey := generateTestKey(t)
+	other := newTestCA(t, "other", otherKey)
+
+	ks := New(WithOrderedAliases())
+	setTestIdentity(t, ks, "a-other", other, otherKey, "client", time.Hour)
+	setTestIdentity(t, ks, "b-expiring", ca, caKey, "client", time.Hour)
+	setTestIdentity(t, ks, "c-lasting", ca, caKey, "client", 2*time.Hour)
+
+	ids, err := ks.TLSIdentities(UnlockOptions{Candidates: [][]byte{[]byte("password")}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a-other", "b-expiring", "c-lasting"}, ids.Aliases())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	serverIDs := newTestServerIdentities(t, ca, caKey)
+
+	peer := handshake(t,
+		&tls.Config{GetClientCertificate: ids.GetClientCertificate, RootCAs: pool, ServerName: "server"},
+		&tls.Config{GetCertificate: serverIDs.GetCertificate, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool},
+	)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), peer.NotAfter, time.Minute)
+
+	cert, err := ids.GetClientCertificate(&tls.CertificateRequestInfo{
+		AcceptableCAs:    [][]byte{[]byte("unknown")},
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		Version:          tls.VersionTLS13,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, cert.Certificate)
+}
+
+func TestTLSIdentitiesServerName(t *testing.T) {
+	caKey := generateTestKey(t)
+	ca := newTestCA(t, "ca", caKey)
+
+	ks := New(WithOrderedAliases())
+	setTestIdentity(t, ks, "api", ca, caKey, "api.example.com", time.Hour)
+	setTestIdentity(t, ks, "wildcard", ca, caKey, "*.example.com", time.Hour)
+
+	ids, err := ks.TLSIdentities(UnlockOptions{Candidates: [][]byte{[]byte("password")}})
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	for name, expected := range map[string]string{"api.example.com": "api.example.com", "www.example.com": "*.example.com"} {
+		peer := handshake(t,
+			&tls.Config{RootCAs: pool, ServerName: name},
+			&tls.Config{GetCertificate: ids.GetCertificate},
+		)
+		assert.Equal(t, []string{expected}, peer.DNSNames, name)
+	}
+
+	_, err = ids.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestTLSIdentitiesLocked(t *testing.T) {
+	caKey := generateTestKey(t)
+	ks := New()
+	setTestIdentity(t, ks, "client", newTestCA(t, "ca", caKey), caKey, "client", time.Hour)
+
+	_, err := ks.TLSIdentities(UnlockOptions{})
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+}
+
+func newTestCA(t *testing.T, name string, key *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	return createTestCertificate(t, template, nil, &key.PublicKey, key)
+}
+
+func setTestIdentity(
+	t *testing.T, ks KeyStore, alias string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string, validity time.Duration,
+) {
+	t.Helper()
+
+	key := generateTestKey(t)
+	cert := createTestCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		DNSNames:    []string{name},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}, ca, &key.PublicKey, caKey)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, ks.SetPrivateKeyEntry(alias, PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   der,
+		CertificateChain: []Certificate{
+			{Type: X509CertificateType, Content: cert.Raw},
+			{Type: X509CertificateType, Content: ca.Raw},
+		},
+	}, []byte("password")))
+}
+
+func newTestServerIdentities(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *TLSIdentities {
+	t.Helper()
+
+	ks := New()
+	setTestIdentity(t, ks, "server", ca, caKey, "server", time.Hour)
+
+	ids, err := ks.TLSIdentities(UnlockOptions{Candidates: [][]byte{[]byte("password")}})
+	require.NoError(t, err)
+
+	return ids
+}
+
+// handshake connects client and server over in-memory connection and returns the leaf certificate
+// the client got from the server or the server got from the client if it asked for one.
+func handshake(t *testing.T, clientConfig, serverConfig *tls.Config) *x509.Certificate {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	client := tls.Client(clientConn, clientConfig)
+	server := tls.Server(serverConn, serverConfig)
+
+	errs := make(chan error, 1)
+
+	go func() { errs <- server.Handshake() }()
+
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-errs)
+
+	if certs := server.ConnectionState().PeerCertificates; len(certs) > 0 {
+		return certs[0]
+	}
+
+	return client.ConnectionState().PeerCertificates[0]
+}
@@ -0,0 +1,149 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CertificateFilter reports whether a trusted certificate should be exported.
+type CertificateFilter func(cert *x509.Certificate) bool
+
+// OnlyCA accepts certificates that are allowed to sign other certificates.
+// Version 1 self-signed certificates are treated as CA certificates because
+// they predate the basic constraints extension.
+func OnlyCA() CertificateFilter {
+	return func(cert *x509.Certificate) bool {
+		if cert.BasicConstraintsValid {
+			return cert.IsCA
+		}
+
+		return cert.Version < 3 && bytes.Equal(cert.RawSubject, cert.RawIssuer) //nolint:gomnd,mnd
+	}
+}
+
+// ValidAt accepts certificates which validity period contains t.
+func ValidAt(t time.Time) CertificateFilter {
+	return func(cert *x509.Certificate) bool {
+		return !t.Before(cert.NotBefore) && !t.After(cert.NotAfter)
+	}
+}
+
+// ExcludeSHA1Signed rejects certificates signed using SHA-1 based signature algorithms.
+func ExcludeSHA1Signed() CertificateFilter {
+	return func(cert *x509.Certificate) bool {
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+// MatchSubject accepts certificates which subject distinguished name equals one of subjects.
+// Subjects are compared with the string form returned by pkix.Name.String.
+func MatchSubject(subjects ...string) CertificateFilter {
+	return func(cert *x509.Certificate) bool {
+		subject := cert.Subject.String()
+		for _, s := range subjects {
+			if s == subject {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// MatchSPKIPin accepts certificates which public key matches one of pins.
+// A pin is base64 encoded SHA-256 digest of the subject public key info as returned by SPKIPin.
+func MatchSPKIPin(pins ...string) CertificateFilter {
+	return func(cert *x509.Certificate) bool {
+		pin := SPKIPin(cert)
+		for _, p := range pins {
+			if p == pin {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// SPKIPin returns base64 encoded SHA-256 digest of the certificate subject public key info.
+func SPKIPin(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// TrustedCertificates returns parsed certificates of all TrustedCertificateEntry
+// entries accepted by every filter. Certificates of types other than X.509 are skipped.
+func (ks KeyStore) TrustedCertificates(filters ...CertificateFilter) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for _, alias := range ks.Aliases() {
+		tce, ok := ks.m[alias].(TrustedCertificateEntry)
+		if !ok || !isX509CertificateType(tce.Certificate.Type) {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(tce.Certificate.Content)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate %q: %w", alias, err)
+		}
+
+		if acceptCertificate(cert, filters) {
+			certs = append(certs, cert)
+		}
+	}
+
+	return certs, nil
+}
+
+// CertPool returns pool of trusted certificates accepted by every filter.
+func (ks KeyStore) CertPool(filters ...CertificateFilter) (*x509.CertPool, error) {
+	certs, err := ks.TrustedCertificates(filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+
+	return pool, nil
+}
+
+// WritePEMBundle writes trusted certificates accepted by every filter into w as a PEM bundle.
+func (ks KeyStore) WritePEMBundle(w io.Writer, filters ...CertificateFilter) error {
+	certs, err := ks.TrustedCertificates(filters...)
+	if err != nil {
+		return err
+	}
+
+	for i, cert := range certs {
+		if err := pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return fmt.Errorf("encode %d certificate: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func acceptCertificate(cert *x509.Certificate, filters []CertificateFilter) bool {
+	for _, filter := range filters {
+		if !filter(cert) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedCertificates(t *testing.T) {
+	now := time.Now()
+
+	caKey := generateTestKey(t)
+	ca := createTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, caKey.Public(), caKey)
+
+	leafKey := generateTestKey(t)
+	leaf := createTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "leaf"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+	}, ca, leafKey.Public(), caKey)
+
+	expiredKey := generateTestKey(t)
+	expired := createTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "expired"},
+		NotBefore:             now.Add(-2 * time.Hour),
+		NotAfter:              now.Add(-time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    x509.ECDSAWithSHA1,
+	}, nil, expiredKey.Public(), expiredKey)
+
+	ks := New(WithOrderedAliases())
+
+	for alias, cert := range map[string]*x509.Certificate{"ca": ca, "leaf": leaf, "expired": expired} {
+		err := ks.SetTrustedCertificateEntry(alias, TrustedCertificateEntry{
+			CreationTime: now,
+			Certificate:  Certificate{Type: "X509", Content: cert.Raw},
+		})
+		require.NoError(t, err)
+	}
+
+	err := ks.SetTrustedCertificateEntry("other", TrustedCertificateEntry{
+		CreationTime: now,
+		Certificate:  Certificate{Type: "other", Content: []byte{1, 2, 3}},
+	})
+	require.NoError(t, err)
+
+	table := []struct {
+		name     string
+		filters  []CertificateFilter
+		expected []*x509.Certificate
+	}{
+		{name: "all", expected: []*x509.Certificate{ca, expired, leaf}},
+		{name: "ca", filters: []CertificateFilter{OnlyCA()}, expected: []*x509.Certificate{ca, expired}},
+		{name: "valid", filters: []CertificateFilter{ValidAt(now)}, expected: []*x509.Certificate{ca, leaf}},
+		{name: "sha1", filters: []CertificateFilter{ExcludeSHA1Signed()}, expected: []*x509.Certificate{ca, leaf}},
+		{name: "subject", filters: []CertificateFilter{MatchSubject("CN=leaf")}, expected: []*x509.Certificate{leaf}},
+		{name: "pin", filters: []CertificateFilter{MatchSPKIPin(SPKIPin(ca))}, expected: []*x509.Certificate{ca}},
+		{name: "combined", filters: []CertificateFilter{OnlyCA(), ValidAt(now)}, expected: []*x509.Certificate{ca}},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, err := ks.TrustedCertificates(tt.filters...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, certs)
+		})
+	}
+}
+
+func TestCertPoolAndPEMBundle(t *testing.T) {
+	ks := New()
+
+	err := ks.SetTrustedCertificateEntry("alias", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	})
+	require.NoError(t, err)
+
+	pool, err := ks.CertPool(OnlyCA())
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(readCertificate(t))
+	require.NoError(t, err)
+
+	expectedPool := x509.NewCertPool()
+	expectedPool.AddCert(cert)
+	assert.True(t, expectedPool.Equal(pool), "unexpected pool content")
+
+	var buf bytes.Buffer
+
+	err = ks.WritePEMBundle(&buf)
+	require.NoError(t, err)
+
+	b, rest := pem.Decode(buf.Bytes())
+	require.NotNil(t, b)
+	assert.Empty(t, rest)
+	assert.Equal(t, readCertificate(t), b.Bytes)
+
+	err = ks.SetTrustedCertificateEntry("broken", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: []byte{1, 2, 3}},
+	})
+	require.NoError(t, err)
+
+	_, err = ks.CertPool()
+	require.Error(t, err)
+}
+
+func generateTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return key
+}
+
+func createTestCertificate(
+	t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer,
+) *x509.Certificate {
+	t.Helper()
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template.SerialNumber = serialNumber
+
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
@@ -0,0 +1,100 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+)
+
+// validatePrivateKeyEntry validates the entry, strictly if strictValidation option is set.
+func (ks KeyStore) validatePrivateKeyEntry(e PrivateKeyEntry) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	if !ks.strictValidation {
+		return nil
+	}
+
+	key, err := parsePrivateKey(e.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	chain := make([]*x509.Certificate, len(e.CertificateChain))
+
+	for i, c := range e.CertificateChain {
+		if chain[i], err = c.parseX509(); err != nil {
+			return fmt.Errorf("parse certificate %d in chain: %w", i, err)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+
+	if !publicKeysEqual(chain[0].PublicKey, key.Public()) {
+		return fmt.Errorf("check certificate 0 in chain: %w", ErrPublicKeyMismatch)
+	}
+
+	for i := 1; i < len(chain); i++ {
+		if !issuedBy(chain[i-1], chain[i]) {
+			return fmt.Errorf("check certificate %d in chain is issued by certificate %d: %w",
+				i-1, i, ErrUnorderedCertificateChain)
+		}
+
+		// Signature is checked without constraints of the issuer and allowing SHA-1 the way Java does.
+		err := chain[i].CheckSignature(chain[i-1].SignatureAlgorithm, chain[i-1].RawTBSCertificate, chain[i-1].Signature)
+		if err != nil {
+			return fmt.Errorf("check signature of certificate %d in chain: %w", i-1, err)
+		}
+	}
+
+	return nil
+}
+
+// issuedBy reports whether the issuer name of the certificate and its authority key id if both are set
+// match the subject of the issuer.
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, issuer.RawSubject) {
+		return false
+	}
+
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId)
+	}
+
+	return true
+}
+
+// validateTrustedCertificateEntry validates the entry, strictly if strictValidation option is set.
+func (ks KeyStore) validateTrustedCertificateEntry(e TrustedCertificateEntry) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	if !ks.strictValidation {
+		return nil
+	}
+
+	if _, err := e.Certificate.Parse(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseX509 parses the certificate returning ErrUnsupportedCertificateType if it is not X.509.
+func (c Certificate) parseX509() (*x509.Certificate, error) {
+	parsed, err := c.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	cert, ok := parsed.(*x509.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("parse %q certificate: %w", c.Type, ErrUnsupportedCertificateType)
+	}
+
+	return cert, nil
+}
@@ -0,0 +1,121 @@
+package keystore
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictValidationPrivateKeyEntry(t *testing.T) {
+	generated := New()
+	password := []byte("password")
+
+	require.NoError(t, generated.GenerateKeyPair("alias", KeyPairOptions{
+		Algorithm: KeyAlgorithmECDSA,
+		Subject:   pkix.Name{CommonName: "localhost"},
+	}, password))
+
+	pke, err := generated.GetPrivateKeyEntry("alias", password)
+	require.NoError(t, err)
+
+	publicKey := entryTestPublicKey(t, generated, "alias")
+	root, intermediate, leaf := issueTestChain(t, publicKey)
+	leafCert := Certificate{Type: "X509", Content: leaf.Raw}
+	intermediateCert := Certificate{Type: "X509", Content: intermediate.Raw}
+	rootCert := Certificate{Type: "X509", Content: root.Raw}
+
+	tampered := cloneBytes(leaf.Raw)
+	tampered[len(tampered)-1] ^= 1
+
+	// Java accepts certificates signed with SHA-1 by issuers which are not certificate authorities.
+	issuerKey := generateTestKey(t)
+	issuer := createTestCertificate(t, &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "issuer"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}, nil, issuerKey.Public(), issuerKey)
+	sha1Leaf := createTestCertificate(t, &x509.Certificate{
+		Subject:            pkix.Name{CommonName: "localhost"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(time.Hour),
+		SignatureAlgorithm: x509.ECDSAWithSHA1,
+	}, issuer, publicKey, issuerKey)
+	sha1Chain := []Certificate{{Type: "X509", Content: sha1Leaf.Raw}, {Type: "X509", Content: issuer.Raw}}
+
+	key := pke.PrivateKey
+	chain := []Certificate{leafCert, intermediateCert, rootCert}
+
+	for name, tc := range map[string]struct {
+		privateKey []byte
+		chain      []Certificate
+		valid      bool
+		err        error
+	}{
+		"valid":            {key, chain, true, nil},
+		"leaf only":        {key, []Certificate{leafCert}, true, nil},
+		"no chain":         {key, nil, true, nil},
+		"sha1 non ca":      {key, sha1Chain, true, nil},
+		"invalid key":      {[]byte{1, 2, 3}, []Certificate{leafCert}, false, nil},
+		"invalid cert":     {key, []Certificate{{Type: "X509", Content: []byte{1, 2, 3}}}, false, nil},
+		"unsupported cert": {key, []Certificate{{Type: "PGP", Content: leaf.Raw}}, false, ErrUnsupportedCertificateType},
+		"key mismatch":     {readPrivateKey(t), chain, false, ErrPublicKeyMismatch},
+		"unordered":        {key, []Certificate{leafCert, rootCert, intermediateCert}, false, ErrUnorderedCertificateChain},
+		"incomplete":       {key, []Certificate{leafCert, rootCert}, false, ErrUnorderedCertificateChain},
+		"bad signature":    {key, []Certificate{{Type: "X509", Content: tampered}, intermediateCert}, false, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			entry := PrivateKeyEntry{CreationTime: time.Now(), PrivateKey: tc.privateKey, CertificateChain: tc.chain}
+
+			ks := New(WithStrictValidation())
+			err := ks.SetPrivateKeyEntry("alias", entry, password)
+
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+
+				if tc.err != nil {
+					require.ErrorIs(t, err, tc.err)
+				} else {
+					require.NotErrorIs(t, err, ErrUnorderedCertificateChain)
+				}
+			}
+
+			assert.Equal(t, tc.valid, ks.IsPrivateKeyEntry("alias"))
+
+			err = ks.SetPrivateKeyEntryWithSource("source", entry, PasswordFunc(func() ([]byte, error) {
+				return []byte("password"), nil
+			}))
+			assert.Equal(t, tc.valid, err == nil)
+
+			require.NoError(t, New().SetPrivateKeyEntry("alias", entry, password))
+		})
+	}
+}
+
+func TestStrictValidationTrustedCertificateEntry(t *testing.T) {
+	ks := New(WithStrictValidation())
+
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}))
+
+	invalid := TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: []byte{1, 2, 3}},
+	}
+	require.Error(t, ks.SetTrustedCertificateEntry("invalid", invalid))
+	assert.False(t, ks.IsTrustedCertificateEntry("invalid"))
+	require.NoError(t, New().SetTrustedCertificateEntry("invalid", invalid))
+
+	err := ks.SetTrustedCertificateEntry("unsupported", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "PGP", Content: readCertificate(t)},
+	})
+	require.ErrorIs(t, err, ErrUnsupportedCertificateType)
+}
@@ -0,0 +1,167 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// VerifyResult describes keystore representation checked by Verify.
+type VerifyResult struct {
+	Version uint32
+	Entries int
+}
+
+// Verify reads keystore representation from r checking its structure and signature without decoding
+// the entries, so memory usage does not depend on the keystore size. The keystore is not changed,
+// only its options are used, e.g. WithLegacyPasswordEncoding.
+// It is strongly recommended to fill password slice with zero after usage.
+func (ks KeyStore) Verify(r io.Reader, password []byte) (VerifyResult, error) {
+	d := decoder{
+		r: r,
+		h: sha1.New(),
+	}
+
+	passwordBytes := passwordBytes(password, ks.legacyPasswordEncoding)
+	defer zeroing(passwordBytes)
+
+	if _, err := d.h.Write(passwordBytes); err != nil {
+		return VerifyResult{}, fmt.Errorf("update digest with password: %w", err)
+	}
+
+	if _, err := d.h.Write(whitenerMessage); err != nil {
+		return VerifyResult{}, fmt.Errorf("update digest with whitener message: %w", err)
+	}
+
+	readMagic, err := d.readUint32()
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("read magic: %w", err)
+	}
+
+	if readMagic != magic {
+		return VerifyResult{}, errors.New("got invalid magic")
+	}
+
+	version, err := d.readUint32()
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("read version: %w", err)
+	}
+
+	if version != version01 && version != version02 {
+		return VerifyResult{}, errors.New("got unknown version")
+	}
+
+	entryNum, err := d.readUint32()
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("read number of entries: %w", err)
+	}
+
+	for i := range entryNum {
+		if err := d.skipEntry(version); err != nil {
+			return VerifyResult{}, fmt.Errorf("read %d entry: %w", i, err)
+		}
+	}
+
+	computedDigest := d.h.Sum(nil)
+	defer zeroing(computedDigest)
+
+	actualDigest, err := d.readBytes(uint32(d.h.Size())) //nolint:gosec
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("read digest: %w", err)
+	}
+
+	if !bytes.Equal(actualDigest, computedDigest) {
+		return VerifyResult{}, fmt.Errorf("got invalid digest: %w", ErrIncorrectPassword)
+	}
+
+	return VerifyResult{Version: version, Entries: int(entryNum)}, nil
+}
+
+// skipBytes feeds num bytes to the digest without keeping them.
+func (d decoder) skipBytes(num uint32) error {
+	if _, err := io.CopyN(d.h, d.r, int64(num)); err != nil {
+		return fmt.Errorf("read %d bytes: %w", num, err)
+	}
+
+	return nil
+}
+
+func (d decoder) skipString() error {
+	strLen, err := d.readUint16()
+	if err != nil {
+		return fmt.Errorf("read length: %w", err)
+	}
+
+	if err := d.skipBytes(uint32(strLen)); err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	return nil
+}
+
+func (d decoder) skipCertificate(version uint32) error {
+	if version == version02 {
+		if err := d.skipString(); err != nil {
+			return fmt.Errorf("read type: %w", err)
+		}
+	}
+
+	certLen, err := d.readUint32()
+	if err != nil {
+		return fmt.Errorf("read length: %w", err)
+	}
+
+	if err := d.skipBytes(certLen); err != nil {
+		return fmt.Errorf("read content: %w", err)
+	}
+
+	return nil
+}
+
+func (d decoder) skipEntry(version uint32) error {
+	tag, err := d.readUint32()
+	if err != nil {
+		return fmt.Errorf("read tag: %w", err)
+	}
+
+	if err := d.skipString(); err != nil {
+		return fmt.Errorf("read alias: %w", err)
+	}
+
+	if _, err := d.readUint64(); err != nil {
+		return fmt.Errorf("read creation timestamp: %w", err)
+	}
+
+	switch tag {
+	case privateKeyTag:
+		length, err := d.readUint32()
+		if err != nil {
+			return fmt.Errorf("read length: %w", err)
+		}
+
+		if err := d.skipBytes(length); err != nil {
+			return fmt.Errorf("read encrypted private key: %w", err)
+		}
+
+		certNum, err := d.readUint32()
+		if err != nil {
+			return fmt.Errorf("read number of certificates: %w", err)
+		}
+
+		for i := range certNum {
+			if err := d.skipCertificate(version); err != nil {
+				return fmt.Errorf("read %d certificate: %w", i, err)
+			}
+		}
+	case trustedCertificateTag:
+		if err := d.skipCertificate(version); err != nil {
+			return fmt.Errorf("read certificate: %w", err)
+		}
+	default:
+		return errors.New("got unknown entry tag")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	content, err := os.ReadFile("./testdata/keystore.jks")
+	require.NoError(t, err)
+
+	result, err := New().Verify(bytes.NewReader(content), []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Version: 2, Entries: 1}, result)
+
+	_, err = New().Verify(bytes.NewReader(content), []byte("wrong"))
+	require.ErrorIs(t, err, ErrIncorrectPassword)
+
+	_, err = New().Verify(bytes.NewReader(content[:len(content)-1]), []byte("password"))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrIncorrectPassword)
+
+	corrupted := bytes.Clone(content)
+	corrupted[len(corrupted)/2] ^= 1
+
+	_, err = New().Verify(bytes.NewReader(corrupted), []byte("password"))
+	require.Error(t, err)
+}
+
+func TestVerifyStoredKeyStore(t *testing.T) {
+	ks := New()
+	require.NoError(t, ks.SetPrivateKeyEntry("key", PrivateKeyEntry{
+		CreationTime: time.Now(),
+		PrivateKey:   bytes.Repeat([]byte{1}, 8<<20),
+		CertificateChain: []Certificate{
+			{Type: "X509", Content: readCertificate(t)},
+			{Type: "X509", Content: readCertificate(t)},
+		},
+	}, []byte("key-password")))
+	require.NoError(t, ks.SetTrustedCertificateEntry("cert", TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  Certificate{Type: "X509", Content: readCertificate(t)},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, ks.Store(&buf, []byte("password")))
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+
+	result, err := New().Verify(bytes.NewReader(buf.Bytes()), []byte("password"))
+
+	runtime.ReadMemStats(&after)
+
+	require.NoError(t, err)
+	assert.Equal(t, VerifyResult{Version: 2, Entries: 2}, result)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20), "memory must not depend on entry size")
+}